@@ -1,36 +1,168 @@
 package midec
 
 import (
+	"bufio"
+	"encoding/binary"
+	"errors"
 	"io"
+	"math"
 )
 
 const tmpLength = 256 * 3
 
+// ErrNotSeekable indicates that going backward was required but the reader does not implement io.Seeker.
+var ErrNotSeekable = errors.New("midec: reader is not seekable")
+
+// peekDiscarder is implemented by bufio.Reader, which the readers passed to the decoders by IsAnimated and Inspect are.
+type peekDiscarder interface {
+	Peek(int) ([]byte, error)
+	Discard(int) (int, error)
+}
+
 // ReadAdvancer is the struct that can skip some bytes reading.
 type ReadAdvancer struct {
 	io.Reader
-	tmp []byte
+	// pd is the reader when it can be read and skipped without copying.
+	pd peekDiscarder
+	// tmp is allocated on the first use, only when the reader is not a peekDiscarder.
+	tmp    []byte
+	offset int64
+
+	visit Visitor
+	// ends is the end offsets of the visited structures containing the current one.
+	ends []int64
 }
 
 // NewReadAdvancer creates ReadAdvancer.
 func NewReadAdvancer(r io.Reader) *ReadAdvancer {
-	var arr [tmpLength]byte
-	return &ReadAdvancer{
-		Reader: r,
-		tmp:    arr[:],
+	a := &ReadAdvancer{Reader: r}
+	a.pd, _ = r.(peekDiscarder)
+	if tr, ok := r.(*traceReader); ok {
+		a.visit = tr.visit
 	}
+	return a
+}
+
+// Read reads from the underlying reader and keeps track of the offset.
+func (a *ReadAdvancer) Read(buf []byte) (int, error) {
+	n, err := a.Reader.Read(buf)
+	a.offset += int64(n)
+	return n, err
 }
 
 // ReadFull is a shorthand for io.ReadFull.
 func (a *ReadAdvancer) ReadFull(buf []byte) (int, error) {
-	return io.ReadFull(a.Reader, buf)
+	return io.ReadFull(a, buf)
+}
+
+// Next reads n bytes, which must be at most 768, and returns them without allocating.
+// The returned slice is valid only until the next read or skip.
+// The errors are the same as ReadFull.
+func (a *ReadAdvancer) Next(n int) ([]byte, error) {
+	if a.pd != nil {
+		b, err := a.pd.Peek(n)
+		if err == nil {
+			// discarding the peeked bytes does not overwrite them
+			_, _ = a.pd.Discard(n)
+			a.offset += int64(n)
+			return b, nil
+		}
+		if err != bufio.ErrBufferFull {
+			d, _ := a.pd.Discard(len(b))
+			a.offset += int64(d)
+			return nil, fullReadError(d, err)
+		}
+	}
+
+	buf := a.scratch()[0:n]
+	if _, err := a.ReadFull(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// ReadBinary is the same as binary.Read, but does not allocate when data is a pointer to a fixed-size integer.
+func (a *ReadAdvancer) ReadBinary(order binary.ByteOrder, data interface{}) error {
+	var n int
+	switch data.(type) {
+	case *int8, *uint8:
+		n = 1
+	case *int16, *uint16:
+		n = 2
+	case *int32, *uint32:
+		n = 4
+	case *int64, *uint64:
+		n = 8
+	default:
+		return binary.Read(a, order, data)
+	}
+
+	b, err := a.Next(n)
+	if err != nil {
+		return err
+	}
+	switch v := data.(type) {
+	case *int8:
+		*v = int8(b[0])
+	case *uint8:
+		*v = b[0]
+	case *int16:
+		*v = int16(order.Uint16(b))
+	case *uint16:
+		*v = order.Uint16(b)
+	case *int32:
+		*v = int32(order.Uint32(b))
+	case *uint32:
+		*v = order.Uint32(b)
+	case *int64:
+		*v = int64(order.Uint64(b))
+	case *uint64:
+		*v = order.Uint64(b)
+	}
+	return nil
+}
+
+// fullReadError converts the error after reading n bytes to the one returned by io.ReadFull.
+func fullReadError(n int, err error) error {
+	if err == io.EOF && n > 0 {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (a *ReadAdvancer) scratch() []byte {
+	if a.tmp == nil {
+		a.tmp = make([]byte, tmpLength)
+	}
+	return a.tmp
 }
 
 // Advance skips some bytes.
+// When the reader implements io.Seeker, more than tmpLength bytes are skipped by seeking instead of reading.
 func (a *ReadAdvancer) Advance(n uint) error {
+	// a negative size converted to uint, which no file can contain
+	if int(n) < 0 {
+		return io.ErrUnexpectedEOF
+	}
+
+	if n > tmpLength {
+		if s, ok := a.Reader.(io.Seeker); ok {
+			// a reader that cannot seek (e.g. a pipe) falls back to reading
+			if _, err := s.Seek(int64(n)-1, io.SeekCurrent); err == nil {
+				return a.afterSeek(int64(n))
+			}
+		}
+	}
+
+	if a.pd != nil {
+		d, err := a.pd.Discard(int(n))
+		a.offset += int64(d)
+		return fullReadError(d, err)
+	}
+
+	tmp := a.scratch()
 	for n >= tmpLength {
-		buf := a.tmp[0:tmpLength]
-		if _, err := a.ReadFull(buf); err != nil {
+		if _, err := a.ReadFull(tmp); err != nil {
 			return err
 		}
 
@@ -38,10 +170,72 @@ func (a *ReadAdvancer) Advance(n uint) error {
 	}
 
 	if n > 0 {
-		buf := a.tmp[0:n]
+		buf := tmp[0:n]
 		if _, err := a.ReadFull(buf); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// afterSeek completes skipping n bytes after seeking n-1 bytes. Since seeking beyond the end is not an error,
+// the last byte is read to detect it.
+func (a *ReadAdvancer) afterSeek(n int64) error {
+	a.offset += n - 1
+
+	if _, err := a.Next(1); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
+// Offset returns the number of bytes read or skipped through ReadAdvancer.
+func (a *ReadAdvancer) Offset() int64 {
+	return a.offset
+}
+
+// Tracing reports whether Visit reports the structures, so that building their names can be skipped otherwise.
+func (a *ReadAdvancer) Tracing() bool {
+	return a.visit != nil
+}
+
+// Visit reports a structure to the Visitor passed to Trace. It does nothing when not tracing.
+// The depth is the number of the structures visited before which contain offset, so a structure
+// should be visited before its children. size is -1 when it extends to the end of the file.
+func (a *ReadAdvancer) Visit(typ string, offset, size int64) {
+	if a.visit == nil {
+		return
+	}
+
+	for len(a.ends) > 0 && a.ends[len(a.ends)-1] <= offset {
+		a.ends = a.ends[:len(a.ends)-1]
+	}
+	a.visit(Structure{Type: typ, Offset: offset, Size: size, Depth: len(a.ends)})
+
+	end := offset + size
+	if size < 0 {
+		end = math.MaxInt64
+	}
+	a.ends = append(a.ends, end)
+}
+
+// SeekTo moves to offset, counted in the same way as Offset.
+// Moving forward is done by Advance, so only moving backward requires the reader to implement io.Seeker.
+func (a *ReadAdvancer) SeekTo(offset int64) error {
+	if offset >= a.offset {
+		return a.Advance(uint(offset - a.offset))
+	}
+
+	s, ok := a.Reader.(io.Seeker)
+	if !ok {
+		return ErrNotSeekable
+	}
+	if _, err := s.Seek(offset-a.offset, io.SeekCurrent); err != nil {
+		return err
+	}
+	a.offset = offset
+	return nil
+}
@@ -1,8 +1,11 @@
 package midec_test
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"testing"
 
 	"github.com/sapphi-red/midec"
@@ -19,15 +22,17 @@ func Test_ReadAdvancer_Advance(t *testing.T) {
 	}
 
 	testcases := []struct {
-		byteLen           int
-		advanceLen int
-		expectedHasError   bool
+		byteLen          int
+		advanceLen       int
+		expectedHasError bool
 	}{
 		{0, 1, true},
 		{1, 1, false},
-		{1, 256 * 3 + 1, true},
-		{256 * 3 + 1, 256 * 3 + 1, false},
-		{256 * 3 + 1, 256 * 3 + 2, true},
+		{1, 256*3 + 1, true},
+		{256*3 + 1, 256*3 + 1, false},
+		{256*3 + 1, 256*3 + 2, true},
+		// a negative size converted to uint
+		{256*3 + 1, -17, true},
 	}
 
 	for _, tc := range testcases {
@@ -43,3 +48,106 @@ func Test_ReadAdvancer_Advance(t *testing.T) {
 		})
 	}
 }
+
+func Test_ReadAdvancer_SeekTo(t *testing.T) {
+	t.Parallel()
+
+	data := []byte{0, 1, 2, 3, 4, 5, 6, 7}
+
+	testcases := []struct {
+		name             string
+		seekable         bool
+		offsets          []int64
+		expectedByte     byte
+		expectedHasError bool
+	}{
+		{"forward", false, []int64{3}, 3, false},
+		{"backward", true, []int64{6, 2}, 2, false},
+		{"backward not seekable", false, []int64{6, 2}, 0, true},
+		{"beyond end", true, []int64{9}, 0, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var r io.Reader = bytes.NewReader(data)
+			if !tc.seekable {
+				r = struct{ io.Reader }{r}
+			}
+			advancer := midec.NewReadAdvancer(r)
+
+			var actualErr error
+			for _, offset := range tc.offsets {
+				if actualErr = advancer.SeekTo(offset); actualErr != nil {
+					break
+				}
+			}
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			buf := make([]byte, 1)
+			if _, err := advancer.ReadFull(buf); err != nil {
+				t.Fatal(err)
+			}
+			if buf[0] != tc.expectedByte {
+				t.Errorf("Byte = %d; want %d", buf[0], tc.expectedByte)
+			}
+			if advancer.Offset() != int64(tc.expectedByte)+1 {
+				t.Errorf("Offset = %d; want %d", advancer.Offset(), tc.expectedByte+1)
+			}
+		})
+	}
+}
+
+func Test_ReadAdvancer_Next(t *testing.T) {
+	t.Parallel()
+
+	data := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+	testcases := []struct {
+		name     string
+		buffered bool
+	}{
+		{"buffered", true},
+		{"not buffered", false},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var r io.Reader = bytes.NewReader(data)
+			if tc.buffered {
+				r = bufio.NewReader(r)
+			}
+			advancer := midec.NewReadAdvancer(r)
+
+			b, err := advancer.Next(2)
+			if err != nil || !bytes.Equal(b, []byte{0, 1}) {
+				t.Errorf("Next = %v, %v; want [0 1], nil", b, err)
+			}
+
+			var u uint32
+			if err := advancer.ReadBinary(binary.BigEndian, &u); err != nil || u != 0x02030405 {
+				t.Errorf("ReadBinary = %#x, %v; want 0x2030405, nil", u, err)
+			}
+			if advancer.Offset() != 6 {
+				t.Errorf("Offset = %d; want 6", advancer.Offset())
+			}
+
+			if _, err := advancer.Next(5); err != io.ErrUnexpectedEOF {
+				t.Errorf("Error = %v; want %v", err, io.ErrUnexpectedEOF)
+			}
+			if _, err := advancer.Next(1); err != io.EOF {
+				t.Errorf("Error = %v; want %v", err, io.EOF)
+			}
+		})
+	}
+}
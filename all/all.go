@@ -0,0 +1,26 @@
+// Package all registers every format implemented in this module.
+//
+//	import _ "github.com/sapphi-red/midec/all"
+package all
+
+import (
+	// register formats
+	_ "github.com/sapphi-red/midec/ani"
+	_ "github.com/sapphi-red/midec/dicom"
+	_ "github.com/sapphi-red/midec/djvu"
+	_ "github.com/sapphi-red/midec/flic"
+	_ "github.com/sapphi-red/midec/gif"
+	_ "github.com/sapphi-red/midec/ico"
+	_ "github.com/sapphi-red/midec/isobmff"
+	_ "github.com/sapphi-red/midec/jpeg"
+	_ "github.com/sapphi-red/midec/jxl"
+	_ "github.com/sapphi-red/midec/lottie"
+	_ "github.com/sapphi-red/midec/matroska"
+	_ "github.com/sapphi-red/midec/mng"
+	_ "github.com/sapphi-red/midec/png"
+	_ "github.com/sapphi-red/midec/svg"
+	_ "github.com/sapphi-red/midec/tiff"
+	_ "github.com/sapphi-red/midec/ugoira"
+	_ "github.com/sapphi-red/midec/webp"
+	_ "github.com/sapphi-red/midec/xcursor"
+)
@@ -0,0 +1,96 @@
+package all_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sapphi-red/midec"
+	_ "github.com/sapphi-red/midec/all"
+)
+
+const modulePath = "github.com/sapphi-red/midec"
+
+func Test_RegisteredFormatNames(t *testing.T) {
+	t.Parallel()
+
+	expected := []string{
+		"ani", "cur", "dicom", "djvu", "dotlottie", "flc", "fli", "gif", "ico", "isobmff", "jng",
+		"jpeg", "jxl", "lottie", "matroska", "mng", "png", "svg", "tiff", "ugoira", "webp", "xcursor",
+	}
+
+	actual := midec.RegisteredFormatNames()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("RegisteredFormatNames = %v; want %v", actual, expected)
+	}
+}
+
+// registersFormat reports whether a non-test file in dir calls midec.Register*.
+func registersFormat(t *testing.T, dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return !found
+			}
+			if x, ok := sel.X.(*ast.Ident); ok && x.Name == "midec" && strings.HasPrefix(sel.Sel.Name, "Register") {
+				found = true
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+func Test_AllFormatsAreImported(t *testing.T) {
+	t.Parallel()
+
+	f, err := parser.ParseFile(token.NewFileSet(), "all.go", nil, parser.ImportsOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := make(map[string]bool)
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		imported[path] = true
+	}
+
+	entries, err := os.ReadDir("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || name == "all" || name == "internal" || name == "cmd" || name == "testdata" || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if !registersFormat(t, filepath.Join("..", name)) {
+			continue
+		}
+
+		if path := modulePath + "/" + name; !imported[path] {
+			t.Errorf("%s registers a format but is not imported by all", path)
+		}
+	}
+}
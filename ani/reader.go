@@ -0,0 +1,221 @@
+// Package ani implements a Windows animated cursor (ANI) detector
+package ani
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/sapphi-red/midec"
+	"github.com/sapphi-red/midec/internal/riff"
+)
+
+const aniHeader = "RIFF????ACON"
+
+// ErrNoHeader indicates that anih chunk was not found.
+var ErrNoHeader = errors.New("midec: (ani) anih chunk not found")
+
+// ErrInvalidHeader indicates that anih chunk was broken.
+var ErrInvalidHeader = errors.New("midec: (ani) invalid anih chunk")
+
+const anihSize = 36
+
+// jiffiesPerSecond is the number of jiffies, the unit of display rates, in a second.
+const jiffiesPerSecond = 60
+
+// Animation is the information of an animated cursor.
+type Animation struct {
+	// Frames is the number of icons stored in the file.
+	Frames int
+	// Steps is the number of frames displayed in one cycle.
+	Steps int
+	// DisplayRate is the default rate of each step in jiffies (1/60 sec).
+	DisplayRate int
+	// Rates is the rate of each step in jiffies. It is nil when there was no rate chunk.
+	Rates []int
+	// Sequence is the index of the frame displayed in each step. It is nil when there was no seq chunk.
+	Sequence []int
+}
+
+// Duration returns the time taken by one cycle.
+func (a *Animation) Duration() time.Duration {
+	if a.Rates == nil {
+		return time.Duration(a.Steps*a.DisplayRate) * time.Second / jiffiesPerSecond
+	}
+
+	total := 0
+	for _, r := range a.Rates {
+		total += r
+	}
+	return time.Duration(total) * time.Second / jiffiesPerSecond
+}
+
+type aniHeaderData struct {
+	frames      uint32
+	steps       uint32
+	displayRate uint32
+}
+
+type decoder struct {
+	riff.Decoder
+}
+
+func (d *decoder) decodeAniHeaderChunk(chd riff.ChunkHeaderData) (ahd aniHeaderData, err error) {
+	if chd.DataSize < anihSize {
+		err = ErrInvalidHeader
+		return
+	}
+
+	var anih struct {
+		Size        uint32
+		Frames      uint32
+		Steps       uint32
+		Width       uint32
+		Height      uint32
+		BitCount    uint32
+		Planes      uint32
+		DisplayRate uint32
+		Flags       uint32
+	}
+	if err = binary.Read(d, binary.LittleEndian, &anih); err != nil {
+		return
+	}
+
+	err = d.SkipChunk(riff.ChunkHeaderData{DataSize: chd.DataSize - anihSize})
+	if err != nil {
+		return
+	}
+
+	return aniHeaderData{
+		frames:      anih.Frames,
+		steps:       anih.Steps,
+		displayRate: anih.DisplayRate,
+	}, nil
+}
+
+// decodeStepsChunk reads rate or seq chunk. Entries more than steps are ignored.
+func (d *decoder) decodeStepsChunk(chd riff.ChunkHeaderData, steps uint32) ([]int, error) {
+	count := chd.DataSize / 4
+	if count > steps {
+		count = steps
+	}
+
+	// not preallocated since count comes from the header and may exceed the file
+	values := []int{}
+	for i := uint32(0); i < count; i++ {
+		v, err := d.ReadUint32()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, int(v))
+	}
+
+	err := d.SkipChunk(riff.ChunkHeaderData{DataSize: chd.DataSize - count*4})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// findAniHeaderChunk skips chunks until anih chunk.
+func (d *decoder) findAniHeaderChunk() (aniHeaderData, error) {
+	if _, err := d.DecodeFormHeader(); err != nil {
+		return aniHeaderData{}, err
+	}
+
+	for {
+		chd, err := d.DecodeChunkHeader()
+		if err != nil {
+			if err == io.EOF {
+				return aniHeaderData{}, ErrNoHeader
+			}
+			return aniHeaderData{}, err
+		}
+
+		if chd.FourCC == "anih" {
+			return d.decodeAniHeaderChunk(chd)
+		}
+
+		if err := d.SkipChunk(chd); err != nil {
+			return aniHeaderData{}, err
+		}
+	}
+}
+
+func (d *decoder) decodeAnimation() (*Animation, error) {
+	ahd, err := d.findAniHeaderChunk()
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Animation{
+		Frames:      int(ahd.frames),
+		Steps:       int(ahd.steps),
+		DisplayRate: int(ahd.displayRate),
+	}
+
+	for {
+		chd, err := d.DecodeChunkHeader()
+		if err != nil {
+			if err == io.EOF {
+				return a, nil
+			}
+			return nil, err
+		}
+
+		switch chd.FourCC {
+		case "rate":
+			if a.Rates, err = d.decodeStepsChunk(chd, ahd.steps); err != nil {
+				return nil, err
+			}
+		case "seq ":
+			if a.Sequence, err = d.decodeStepsChunk(chd, ahd.steps); err != nil {
+				return nil, err
+			}
+		default:
+			if err := d.SkipChunk(chd); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (d *decoder) inspect() (*midec.Info, error) {
+	a, err := d.decodeAnimation()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &midec.Info{Kind: midec.KindStatic, Frames: a.Frames}
+	if a.Frames >= 2 && a.Steps >= 2 {
+		// an animated cursor is played repeatedly while it is shown
+		info.Kind = midec.KindAnimated
+		info.Loops = midec.LoopInfinite
+		info.Duration = a.Duration()
+	}
+	return info, nil
+}
+
+// DecodeAnimation reads anih chunk and the optional rate and seq chunks.
+func DecodeAnimation(r io.Reader) (*Animation, error) {
+	d := decoder{*riff.NewDecoder(r, binary.LittleEndian)}
+	return d.decodeAnimation()
+}
+
+func isAnimated(r io.Reader) (bool, error) {
+	info, err := inspect(r)
+	if err != nil {
+		return false, err
+	}
+	return info.Kind.IsMultiImage(), nil
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{*riff.NewDecoder(r, binary.LittleEndian)}
+	return d.inspect()
+}
+
+func init() {
+	midec.RegisterInspectableFormat("ani", aniHeader, isAnimated, inspect)
+}
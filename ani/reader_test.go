@@ -0,0 +1,146 @@
+package ani
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sapphi-red/midec"
+)
+
+const testdataFolder = "../testdata/ani/"
+
+func Test_isAnimated(t *testing.T) {
+	t.Parallel()
+
+	runIsAnimated := func(filename string) (bool, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return isAnimated(fp)
+	}
+
+	testcases := []struct {
+		filename           string
+		expectedIsAnimated bool
+		expectedHasError   bool
+	}{
+		{"animated.ani", true, false},
+		{"animated-rate-seq.ani", true, false},
+		{"static.ani", false, false},
+		{"static-1frame.ani", false, false},
+		{"invalid-noheader.ani", false, true},
+		{"invalid-header.ani", false, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actualIsAnimated, actualErr := runIsAnimated(tc.filename)
+			if tc.expectedIsAnimated != actualIsAnimated {
+				t.Errorf("IsAnimated = %t; want %t", actualIsAnimated, tc.expectedIsAnimated)
+			}
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+		})
+	}
+}
+
+func Test_DecodeAnimation(t *testing.T) {
+	t.Parallel()
+
+	runDecodeAnimation := func(filename string) (*Animation, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return DecodeAnimation(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expected         *Animation
+		expectedDuration time.Duration
+		expectedHasError bool
+	}{
+		{"animated.ani", &Animation{Frames: 3, Steps: 3, DisplayRate: 10}, 30 * time.Second / 60, false},
+		{"animated-rate-seq.ani", &Animation{
+			Frames:      2,
+			Steps:       4,
+			DisplayRate: 10,
+			Rates:       []int{5, 10, 15, 20},
+			Sequence:    []int{0, 1, 0, 1},
+		}, 50 * time.Second / 60, false},
+		{"static.ani", &Animation{Frames: 1, Steps: 1, DisplayRate: 10}, 10 * time.Second / 60, false},
+		{"invalid-noheader.ani", nil, 0, true},
+		{"invalid-chunk-header.ani", nil, 0, true},
+		{"invalid-rate-chunk.ani", nil, 0, true},
+		// the sizes of the header and the rate chunk are too large for the file
+		{"invalid-rate-size.ani", nil, 0, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runDecodeAnimation(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Animation = %+v; want %+v", actual, tc.expected)
+			}
+			if actual.Duration() != tc.expectedDuration {
+				t.Errorf("Duration = %v; want %v", actual.Duration(), tc.expectedDuration)
+			}
+		})
+	}
+}
+
+func Test_inspect(t *testing.T) {
+	t.Parallel()
+
+	runInspect := func(filename string) (*midec.Info, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return inspect(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expected         *midec.Info
+		expectedHasError bool
+	}{
+		{"animated.ani", &midec.Info{Kind: midec.KindAnimated, Frames: 3, Loops: midec.LoopInfinite, Duration: 30 * time.Second / 60}, false},
+		{"animated-rate-seq.ani", &midec.Info{Kind: midec.KindAnimated, Frames: 2, Loops: midec.LoopInfinite, Duration: 50 * time.Second / 60}, false},
+		{"static.ani", &midec.Info{Kind: midec.KindStatic, Frames: 1}, false},
+		{"invalid-rate-chunk.ani", nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runInspect(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Info = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
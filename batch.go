@@ -0,0 +1,149 @@
+package midec
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"time"
+)
+
+// Source is an input of InspectAll.
+type Source struct {
+	// Name identifies the source in the Result, such as a path or a URL.
+	Name string
+	// Open opens the source with a context which is done when Timeout elapses or InspectAll is canceled.
+	// The reader is closed after inspecting when it implements io.Closer.
+	Open func(ctx context.Context) (io.Reader, error)
+	// Timeout is the time allowed to open and inspect the source. There is no limit when 0.
+	Timeout time.Duration
+}
+
+// Result is the result of a Source.
+type Result struct {
+	Name string
+	// Info is nil when Err is not nil.
+	Info *Info
+	Err  error
+}
+
+// ctxReader fails reading once ctx is done, so that inspecting a slow source stops at the timeout.
+type ctxReader struct {
+	io.Reader
+	ctx context.Context
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.Reader.Read(p)
+}
+
+// Seek implements io.Seeker when the underlying reader does.
+func (c *ctxReader) Seek(offset int64, whence int) (int64, error) {
+	s, ok := c.Reader.(io.Seeker)
+	if !ok {
+		return 0, ErrNotSeekable
+	}
+	return s.Seek(offset, whence)
+}
+
+type batchJob struct {
+	src Source
+	out chan<- Result
+}
+
+// inspectSource opens and inspects src.
+func inspectSource(ctx context.Context, src Source) Result {
+	res := Result{Name: src.Name}
+	if src.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, src.Timeout)
+		defer cancel()
+	}
+
+	r, err := src.Open(ctx)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	// the buffer is taken from readerPool, so it is reused across the sources
+	res.Info, res.Err = Inspect(&ctxReader{r, ctx})
+	return res
+}
+
+// InspectAll inspects the sources received from inputs with workers goroutines (runtime.NumCPU() when 0 or less).
+// The results are sent in the order of inputs, and the channel is closed when inputs is closed and
+// all sources are done, or ctx is canceled.
+func InspectAll(ctx context.Context, inputs <-chan Source, workers int) <-chan Result {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan batchJob)
+	// pending is the result slots in the order of inputs. Its capacity bounds how far
+	// the workers can go ahead of a slow source.
+	pending := make(chan chan Result, workers)
+	results := make(chan Result)
+
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		for {
+			var src Source
+			select {
+			case s, ok := <-inputs:
+				if !ok {
+					return
+				}
+				src = s
+			case <-ctx.Done():
+				return
+			}
+
+			// a slot is buffered so that a worker never waits for the results to be received
+			out := make(chan Result, 1)
+			select {
+			case pending <- out:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- batchJob{src, out}:
+			case <-ctx.Done():
+				out <- Result{Name: src.Name, Err: ctx.Err()}
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for j := range jobs {
+				j.out <- inspectSource(ctx, j.src)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(results)
+		for out := range pending {
+			var res Result
+			select {
+			case res = <-out:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case results <- res:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return results
+}
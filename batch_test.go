@@ -0,0 +1,135 @@
+package midec_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sapphi-red/midec"
+)
+
+func fileSource(filename string) midec.Source {
+	return midec.Source{
+		Name: filename,
+		Open: func(ctx context.Context) (io.Reader, error) {
+			return os.Open(testdataFolder + filename)
+		},
+	}
+}
+
+// slowReader returns a byte per Read after sleeping.
+type slowReader struct {
+	*os.File
+}
+
+func (s slowReader) Read(p []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	return s.File.Read(p[:1])
+}
+
+func Test_InspectAll(t *testing.T) {
+	t.Parallel()
+
+	errOpen := errors.New("open")
+
+	// the first source finishes last
+	delayed := fileSource("png/static.png")
+	open := delayed.Open
+	delayed.Open = func(ctx context.Context) (io.Reader, error) {
+		time.Sleep(20 * time.Millisecond)
+		return open(ctx)
+	}
+
+	sources := []midec.Source{
+		delayed,
+		fileSource("gif/animated.gif"),
+		{Name: "error", Open: func(ctx context.Context) (io.Reader, error) { return nil, errOpen }},
+		fileSource("ugoira/animated.zip"),
+		{
+			Name: "timeout",
+			Open: func(ctx context.Context) (io.Reader, error) {
+				fp, err := os.Open(testdataFolder + "gif/animated.gif")
+				return slowReader{fp}, err
+			},
+			Timeout: 10 * time.Millisecond,
+		},
+		fileSource("gif/invalid-block-unknown.gif"),
+	}
+
+	testcases := []struct {
+		expectedFormat string
+		expectedErr    error
+	}{
+		{"png", nil},
+		{"gif", nil},
+		{"", errOpen},
+		{"ugoira", nil},
+		{"", context.DeadlineExceeded},
+		{"", nil},
+	}
+
+	inputs := make(chan midec.Source)
+	go func() {
+		defer close(inputs)
+		for _, src := range sources {
+			inputs <- src
+		}
+	}()
+
+	i := 0
+	for res := range midec.InspectAll(context.Background(), inputs, 3) {
+		if i >= len(testcases) {
+			t.Fatalf("Results = %d or more; want %d", i+1, len(testcases))
+		}
+		tc := testcases[i]
+
+		if res.Name != sources[i].Name {
+			t.Errorf("%d: Name = %s; want %s", i, res.Name, sources[i].Name)
+		}
+		// the corrupt file has an error other than the expected ones
+		if tc.expectedFormat == "" && tc.expectedErr == nil {
+			if res.Err == nil {
+				t.Errorf("%s: Error = nil; want HasError = true", res.Name)
+			}
+		} else if !errors.Is(res.Err, tc.expectedErr) {
+			t.Errorf("%s: Error = %v; want %v", res.Name, res.Err, tc.expectedErr)
+		}
+		if res.Info != nil && res.Info.Format != tc.expectedFormat {
+			t.Errorf("%s: Format = %s; want %s", res.Name, res.Info.Format, tc.expectedFormat)
+		}
+		if (res.Info == nil) != (res.Err != nil) {
+			t.Errorf("%s: Info = %+v, Error = %v; want either of them", res.Name, res.Info, res.Err)
+		}
+		i++
+	}
+	if i != len(testcases) {
+		t.Errorf("Results = %d; want %d", i, len(testcases))
+	}
+}
+
+func Test_InspectAll_Cancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// inputs is never closed
+	inputs := make(chan midec.Source, 1)
+	inputs <- fileSource("gif/animated.gif")
+
+	results := midec.InspectAll(ctx, inputs, 1)
+	if res := <-results; res.Err != nil {
+		t.Errorf("Error = %v; want HasError = false", res.Err)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-results:
+		if ok {
+			t.Errorf("Results were sent after canceling")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Results were not closed after canceling")
+	}
+}
@@ -2,14 +2,15 @@ package midec_test
 
 import (
 	"image/gif"
+	"io"
 	"os"
 	"testing"
 
 	"github.com/sapphi-red/midec"
 	_ "github.com/sapphi-red/midec/gif"
+	_ "github.com/sapphi-red/midec/isobmff"
 	_ "github.com/sapphi-red/midec/png"
 	_ "github.com/sapphi-red/midec/webp"
-	_ "github.com/sapphi-red/midec/isobmff"
 )
 
 func loadFile(file string) *os.File {
@@ -20,6 +21,44 @@ func loadFile(file string) *os.File {
 	return fp
 }
 
+// raceEnabled is set by race_test.go, since sync.Pool drops items randomly with the race detector.
+var raceEnabled bool
+
+// Test_Allocs asserts the allocations of IsAnimated. It is a test rather than a part of the benchmarks,
+// since the benchmarks do not run with go test by default. The benchmarks report the allocations.
+func Test_Allocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool does not reuse all items with the race detector")
+	}
+
+	testcases := []struct {
+		filename       string
+		expectedAllocs float64
+	}{
+		{"gif/animated.gif", 1},
+		{"png/animated.png", 1},
+		{"webp/animated.webp", 1},
+		{"isobmff/animated.avif", 4},
+	}
+
+	for _, tc := range testcases {
+		fp := loadFile(tc.filename)
+		defer fp.Close()
+
+		allocs := testing.AllocsPerRun(100, func() {
+			if _, err := fp.Seek(0, io.SeekStart); err != nil {
+				panic(err)
+			}
+			if _, err := midec.IsAnimated(fp); err != nil {
+				panic(err)
+			}
+		})
+		if allocs > tc.expectedAllocs {
+			t.Errorf("%s: Allocs = %v; want <= %v", tc.filename, allocs, tc.expectedAllocs)
+		}
+	}
+}
+
 func loadGif() *os.File {
 	return loadFile("gif/animated.gif")
 }
@@ -40,6 +79,7 @@ func BenchmarkGIF_ImageGIF(b *testing.B) {
 }
 
 func BenchmarkGIF_Midec(b *testing.B) {
+	b.ReportAllocs()
 	fp := loadGif()
 	for i := 0; i < b.N; i++ {
 		_, err := midec.IsAnimated(fp)
@@ -54,6 +94,7 @@ func BenchmarkGIF_Midec(b *testing.B) {
 }
 
 func BenchmarkPNG_Midec(b *testing.B) {
+	b.ReportAllocs()
 	fp := loadFile("png/animated.png")
 	for i := 0; i < b.N; i++ {
 		_, err := midec.IsAnimated(fp)
@@ -68,6 +109,7 @@ func BenchmarkPNG_Midec(b *testing.B) {
 }
 
 func BenchmarkWebP_Midec(b *testing.B) {
+	b.ReportAllocs()
 	fp := loadFile("webp/animated.webp")
 	for i := 0; i < b.N; i++ {
 		_, err := midec.IsAnimated(fp)
@@ -82,6 +124,7 @@ func BenchmarkWebP_Midec(b *testing.B) {
 }
 
 func BenchmarkHEIFAVIF_Midec(b *testing.B) {
+	b.ReportAllocs()
 	fp := loadFile("isobmff/animated.avif")
 	for i := 0; i < b.N; i++ {
 		_, err := midec.IsAnimated(fp)
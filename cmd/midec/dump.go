@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sapphi-red/midec"
+)
+
+// node is a structure with its children.
+type node struct {
+	Type     string  `json:"type"`
+	Offset   int64   `json:"offset"`
+	Size     int64   `json:"size"` // -1 when it extends to the end of the file
+	Children []*node `json:"children,omitempty"`
+}
+
+// dumpResult is the output of the dump subcommand in JSON.
+type dumpResult struct {
+	result
+	Structures []*node `json:"structures"`
+}
+
+// treeBuilder builds the tree of structures from the depths.
+type treeBuilder struct {
+	roots []*node
+	// stack is the last node of each depth.
+	stack []*node
+}
+
+func (b *treeBuilder) visit(s midec.Structure) {
+	n := &node{Type: s.Type, Offset: s.Offset, Size: s.Size}
+	if s.Depth > len(b.stack) {
+		// never happens as long as the decoders visit parents first
+		s.Depth = len(b.stack)
+	}
+	b.stack = append(b.stack[:s.Depth], n)
+
+	if s.Depth == 0 {
+		b.roots = append(b.roots, n)
+		return
+	}
+	parent := b.stack[s.Depth-1]
+	parent.Children = append(parent.Children, n)
+}
+
+func writeTree(w io.Writer, nodes []*node, depth int) {
+	for _, n := range nodes {
+		fmt.Fprintf(w, "%s%s offset=%d size=%d\n", strings.Repeat("  ", depth), n.Type, n.Offset, n.Size)
+		writeTree(w, n.Children, depth+1)
+	}
+}
+
+func runDump(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("midec dump", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: midec dump [--json] file")
+		flags.PrintDefaults()
+	}
+	jsonOutput := flags.Bool("json", false, "print the tree as JSON")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+
+	// the structures read before an error are also printed
+	var b treeBuilder
+	res, code := inspect(flags.Arg(0), stdin, b.visit)
+
+	if *jsonOutput {
+		structures := b.roots
+		if structures == nil {
+			structures = []*node{}
+		}
+		if err := json.NewEncoder(stdout).Encode(dumpResult{res, structures}); err != nil {
+			fmt.Fprintln(stderr, "midec:", err)
+			return exitError
+		}
+		return code
+	}
+
+	writeTree(stdout, b.roots, 0)
+	if res.Error != "" {
+		fmt.Fprintln(stderr, "midec:", res)
+	} else {
+		fmt.Fprintln(stdout, res)
+	}
+	return code
+}
@@ -0,0 +1,217 @@
+// Command midec inspects image files and prints whether they are animated.
+//
+// Usage:
+//
+//	midec [--json] [file ...]
+//	midec scan [--format jsonl|csv] [--workers n] [--include glob] [--exclude glob] [--max-size bytes] dir
+//	midec dump [--json] file
+//
+// It reads the standard input when no files are given or the file is "-".
+// The scan subcommand walks dir and writes a report to the standard output and a summary to the standard error.
+// The dump subcommand prints the blocks, chunks or boxes read while inspecting file, with the same exit code.
+//
+// The exit code is one of the following. When the files have different ones, the first one in the order
+// 2, 4, 3, 1, 0 wins, so that a file which could not be read is never hidden by the others.
+//
+//	0  all files are animated
+//	1  a file is static
+//	2  a file could not be read, or the usage was wrong
+//	3  a file is in an unknown format
+//	4  a file is corrupt
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/sapphi-red/midec"
+	_ "github.com/sapphi-red/midec/all"
+)
+
+const (
+	exitAnimated = iota
+	exitStatic
+	exitError
+	exitUnknownFormat
+	exitCorrupt
+)
+
+const stdinName = "-"
+
+// exitPriority is the precedence of the exit codes among the files. A larger one wins.
+var exitPriority = [...]int{
+	exitAnimated:      0,
+	exitStatic:        1,
+	exitUnknownFormat: 2,
+	exitCorrupt:       3,
+	exitError:         4,
+}
+
+// worseExitCode returns the one of a and b with the higher precedence.
+func worseExitCode(a, b int) int {
+	if exitPriority[b] > exitPriority[a] {
+		return b
+	}
+	return a
+}
+
+// result is a line of the output.
+type result struct {
+	Path     string `json:"path"`
+	Format   string `json:"format,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	Animated bool   `json:"animated"`
+	// Frames, Loops, Width, Height and Duration are 0 when unknown. Loops is -1 when played forever.
+	Frames   int     `json:"frames"`
+	Loops    int     `json:"loops"`
+	Width    int     `json:"width"`
+	Height   int     `json:"height"`
+	Duration float64 `json:"duration"` // seconds
+	Error    string  `json:"error,omitempty"`
+}
+
+func newResult(path string, info *midec.Info) result {
+	return result{
+		Path:     path,
+		Format:   info.Format,
+		Kind:     info.Kind.String(),
+		Animated: info.Kind.IsMultiImage(),
+		Frames:   info.Frames,
+		Loops:    info.Loops,
+		Width:    info.Width,
+		Height:   info.Height,
+		Duration: info.Duration.Seconds(),
+	}
+}
+
+func (res result) String() string {
+	if res.Error != "" {
+		return res.Path + ": " + res.Error
+	}
+
+	s := fmt.Sprintf("%s: format=%s kind=%s animated=%t", res.Path, res.Format, res.Kind, res.Animated)
+	if res.Frames > 0 {
+		s += " frames=" + strconv.Itoa(res.Frames)
+	}
+	switch {
+	case res.Loops == midec.LoopInfinite:
+		s += " loops=infinite"
+	case res.Loops > 0:
+		s += " loops=" + strconv.Itoa(res.Loops)
+	}
+	if res.Width > 0 && res.Height > 0 {
+		s += fmt.Sprintf(" size=%dx%d", res.Width, res.Height)
+	}
+	if res.Duration > 0 {
+		s += " duration=" + strconv.FormatFloat(res.Duration, 'f', -1, 64) + "s"
+	}
+	return s
+}
+
+// bytesFile is the standard input read into memory. It keeps io.Seeker unlike ioutil.NopCloser.
+type bytesFile struct {
+	*bytes.Reader
+}
+
+func (bytesFile) Close() error {
+	return nil
+}
+
+// open returns the file, or the whole standard input since some formats need io.Seeker.
+func open(path string, stdin io.Reader) (io.ReadCloser, error) {
+	if path != stdinName {
+		return os.Open(path)
+	}
+
+	b, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		return nil, err
+	}
+	return bytesFile{bytes.NewReader(b)}, nil
+}
+
+// inspect inspects the file. The structures are reported to visit when it is not nil.
+func inspect(path string, stdin io.Reader, visit midec.Visitor) (result, int) {
+	f, err := open(path, stdin)
+	if err != nil {
+		return result{Path: path, Error: err.Error()}, exitError
+	}
+	defer f.Close()
+
+	var info *midec.Info
+	if visit != nil {
+		info, err = midec.Trace(f, visit)
+	} else {
+		info, err = midec.Inspect(f)
+	}
+	switch {
+	case errors.Is(err, midec.ErrFormat):
+		return result{Path: path, Error: err.Error()}, exitUnknownFormat
+	case err != nil:
+		return result{Path: path, Error: err.Error()}, exitCorrupt
+	}
+
+	res := newResult(path, info)
+	if res.Animated {
+		return res, exitAnimated
+	}
+	return res, exitStatic
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "scan":
+			return runScan(args[1:], stdout, stderr)
+		case "dump":
+			return runDump(args[1:], stdin, stdout, stderr)
+		}
+	}
+
+	flags := flag.NewFlagSet("midec", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: midec [--json] [file ...]")
+		flags.PrintDefaults()
+	}
+	jsonOutput := flags.Bool("json", false, "print a JSON object per line")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{stdinName}
+	}
+
+	enc := json.NewEncoder(stdout)
+	code := exitAnimated
+	for _, path := range paths {
+		res, c := inspect(path, stdin, nil)
+		code = worseExitCode(code, c)
+
+		switch {
+		case *jsonOutput:
+			if err := enc.Encode(res); err != nil {
+				fmt.Fprintln(stderr, "midec:", err)
+				return exitError
+			}
+		case res.Error != "":
+			fmt.Fprintln(stderr, "midec:", res)
+		default:
+			fmt.Fprintln(stdout, res)
+		}
+	}
+	return code
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sapphi-red/midec"
+)
+
+const testdataFolder = "../../testdata/"
+
+func Test_run(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name         string
+		args         []string
+		expectedCode int
+	}{
+		{"animated", []string{testdataFolder + "gif/loop.gif"}, exitAnimated},
+		{"multi-image", []string{testdataFolder + "tiff/multipage-backward.tif"}, exitAnimated},
+		{"static", []string{testdataFolder + "png/static.png"}, exitStatic},
+		{"video", []string{testdataFolder + "matroska/video.webm"}, exitStatic},
+		{"unknown format", []string{testdataFolder + "png/invalid-header.png"}, exitUnknownFormat},
+		{"corrupt", []string{testdataFolder + "gif/invalid-block-unknown.gif"}, exitCorrupt},
+		{"not found", []string{testdataFolder + "notfound.gif"}, exitError},
+		{"unknown flag", []string{"--unknown"}, exitError},
+		{"static wins", []string{testdataFolder + "gif/loop.gif", testdataFolder + "png/static.png"}, exitStatic},
+		{"corrupt wins", []string{testdataFolder + "png/invalid-header.png", testdataFolder + "gif/invalid-block-unknown.gif"}, exitCorrupt},
+		{"error wins", []string{testdataFolder + "gif/invalid-block-unknown.gif", testdataFolder + "notfound.gif", testdataFolder + "png/invalid-header.png"}, exitError},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			actualCode := run(tc.args, strings.NewReader(""), &stdout, &stderr)
+			if actualCode != tc.expectedCode {
+				t.Errorf("Code = %d; want %d (stderr: %s)", actualCode, tc.expectedCode, stderr.String())
+			}
+		})
+	}
+}
+
+func Test_run_Plain(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	run([]string{testdataFolder + "gif/loop.gif"}, strings.NewReader(""), &stdout, &stderr)
+
+	expected := testdataFolder + "gif/loop.gif: format=gif kind=animated animated=true frames=17 loops=infinite size=242x175 duration=1.99s\n"
+	if stdout.String() != expected {
+		t.Errorf("Output = %q; want %q", stdout.String(), expected)
+	}
+}
+
+func Test_run_JSONStdin(t *testing.T) {
+	t.Parallel()
+
+	// zip formats need io.Seeker, which the standard input does not have
+	b, err := os.ReadFile(testdataFolder + "ugoira/animated.zip")
+	if err != nil {
+		panic(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--json"}, bytes.NewReader(b), &stdout, &stderr)
+	if code != exitAnimated {
+		t.Errorf("Code = %d; want %d (stderr: %s)", code, exitAnimated, stderr.String())
+	}
+
+	var actual result
+	if err := json.Unmarshal(stdout.Bytes(), &actual); err != nil {
+		t.Fatalf("Error = %v; want HasError = false", err)
+	}
+	expected := result{Path: "-", Format: "ugoira", Kind: "animated", Animated: true, Frames: 3, Duration: 0.5}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Result = %+v; want %+v", actual, expected)
+	}
+}
+
+func Test_run_Scan(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expected     string
+	}{
+		{
+			"csv",
+			[]string{"scan", "--format", "csv", "--include", "loop.gif", testdataFolder + "gif"},
+			0,
+			"path,size,verdict,format,kind,frames,loops,width,height,duration,error\n" +
+				"loop.gif,13084,animated,gif,animated,17,-1,242,175,1.99,\n",
+		},
+		{"unknown format", []string{"scan", "--format", "xml", testdataFolder}, exitError, ""},
+		{"bad glob", []string{"scan", "--exclude", "[", testdataFolder}, exitError, ""},
+		{"no dir", []string{"scan"}, exitError, ""},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			actualCode := run(tc.args, strings.NewReader(""), &stdout, &stderr)
+			if actualCode != tc.expectedCode {
+				t.Errorf("Code = %d; want %d (stderr: %s)", actualCode, tc.expectedCode, stderr.String())
+			}
+			if stdout.String() != tc.expected {
+				t.Errorf("Output = %q; want %q", stdout.String(), tc.expected)
+			}
+		})
+	}
+}
+
+func Test_run_Dump(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expected     string
+	}{
+		{
+			"tree",
+			[]string{"dump", testdataFolder + "djvu/multipage.djvu"},
+			exitAnimated,
+			"FORM DJVM offset=4 size=204\n" +
+				"  DIRM offset=16 size=36\n" +
+				"  FORM offset=52 size=24\n" +
+				"  FORM offset=76 size=44\n" +
+				"  FORM offset=120 size=44\n" +
+				"  FORM offset=164 size=44\n" +
+				testdataFolder + "djvu/multipage.djvu: format=djvu kind=multi-image animated=true frames=3\n",
+		},
+		{
+			"json",
+			[]string{"dump", "--json", testdataFolder + "png/static.png"},
+			exitStatic,
+			`{"path":"` + testdataFolder + `png/static.png","format":"png","kind":"static","animated":false,"frames":1,"loops":0,"width":242,"height":175,"duration":0,` +
+				`"structures":[{"type":"signature","offset":0,"size":8},{"type":"IHDR","offset":8,"size":25},{"type":"sRGB","offset":33,"size":13},` +
+				`{"type":"gAMA","offset":46,"size":16},{"type":"pHYs","offset":62,"size":21},{"type":"IDAT","offset":83,"size":8033}]}` + "\n",
+		},
+		{
+			"corrupt",
+			[]string{"dump", testdataFolder + "gif/invalid-block-unknown.gif"},
+			exitCorrupt,
+			"Header offset=0 size=781\n",
+		},
+		{"no file", []string{"dump"}, exitError, ""},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			actualCode := run(tc.args, strings.NewReader(""), &stdout, &stderr)
+			if actualCode != tc.expectedCode {
+				t.Errorf("Code = %d; want %d (stderr: %s)", actualCode, tc.expectedCode, stderr.String())
+			}
+			if stdout.String() != tc.expected {
+				t.Errorf("Output = %q; want %q", stdout.String(), tc.expected)
+			}
+		})
+	}
+}
+
+func Test_treeBuilder(t *testing.T) {
+	t.Parallel()
+
+	var b treeBuilder
+	for _, s := range []midec.Structure{
+		{Type: "a", Depth: 0},
+		{Type: "b", Depth: 1},
+		{Type: "c", Depth: 2},
+		{Type: "d", Depth: 1},
+		{Type: "e", Depth: 0},
+	} {
+		b.visit(s)
+	}
+
+	expected := []*node{
+		{Type: "a", Children: []*node{
+			{Type: "b", Children: []*node{{Type: "c"}}},
+			{Type: "d"},
+		}},
+		{Type: "e"},
+	}
+	if !reflect.DeepEqual(b.roots, expected) {
+		t.Errorf("Tree = %+v; want %+v", b.roots, expected)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sapphi-red/midec/scan"
+)
+
+// globsFlag is a flag which can be specified multiple times.
+type globsFlag []string
+
+func (g *globsFlag) String() string {
+	return strings.Join(*g, ",")
+}
+
+func (g *globsFlag) Set(s string) error {
+	*g = append(*g, s)
+	return nil
+}
+
+func runScan(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("midec scan", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: midec scan [--format jsonl|csv] [--workers n] [--include glob] [--exclude glob] [--max-size bytes] dir")
+		flags.PrintDefaults()
+	}
+
+	var opts scan.Options
+	format := flags.String("format", "jsonl", "report format (jsonl or csv)")
+	flags.IntVar(&opts.Workers, "workers", 0, "number of files inspected at the same time (default the number of CPUs)")
+	flags.Var((*globsFlag)(&opts.Include), "include", "glob of files to inspect, matched against the base name unless it has '/' (repeatable)")
+	flags.Var((*globsFlag)(&opts.Exclude), "exclude", "glob of files not to inspect (repeatable)")
+	flags.Int64Var(&opts.MaxSize, "max-size", 0, "skip files larger than this size in bytes (default no limit)")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+	for _, globs := range [][]string{opts.Include, opts.Exclude} {
+		if err := scan.ValidateGlobs(globs); err != nil {
+			fmt.Fprintln(stderr, "midec:", err)
+			return exitError
+		}
+	}
+
+	var w scan.Writer
+	switch *format {
+	case "jsonl":
+		w = scan.NewJSONLWriter(stdout)
+	case "csv":
+		w = scan.NewCSVWriter(stdout)
+	default:
+		fmt.Fprintln(stderr, "midec: unknown report format:", *format)
+		return exitError
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	summary := scan.NewSummary()
+	for res := range scan.Scan(ctx, os.DirFS(flags.Arg(0)), ".", opts) {
+		summary.Add(res)
+		if err := w.Write(res); err != nil {
+			fmt.Fprintln(stderr, "midec:", err)
+			return exitError
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintln(stderr, "midec:", err)
+		return exitError
+	}
+
+	if _, err := summary.WriteTo(stderr); err != nil {
+		return exitError
+	}
+	return 0
+}
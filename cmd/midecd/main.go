@@ -0,0 +1,80 @@
+// Command midecd is an HTTP server which inspects images with midec.
+//
+// Usage:
+//
+//	midecd [--addr :8080] [--max-body bytes] [--max-concurrent n] [--origin scheme://host[:port]] [--fetch-timeout duration]
+//
+// Endpoints:
+//
+//	POST /inspect        inspects the raw body, or each file part of a multipart/form-data body
+//	POST /inspect?url=   fetches the URL from an origin given by --origin and inspects it
+//	GET  /metrics        metrics in the Prometheus text format
+//
+// The result is a JSON object for a raw body or a URL, and a JSON array for a multipart body.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	_ "github.com/sapphi-red/midec/all"
+)
+
+// originsFlag is a flag which can be specified multiple times.
+type originsFlag []string
+
+func (o *originsFlag) String() string {
+	return strings.Join(*o, ",")
+}
+
+func (o *originsFlag) Set(s string) error {
+	*o = append(*o, s)
+	return nil
+}
+
+func parseFlags(args []string, stderr io.Writer) (string, config, error) {
+	flags := flag.NewFlagSet("midecd", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	var cfg config
+	addr := flags.String("addr", ":8080", "address to listen on")
+	flags.Int64Var(&cfg.maxBody, "max-body", 32<<20, "maximum size in bytes of a body or a fetched object")
+	flags.IntVar(&cfg.maxConcurrent, "max-concurrent", runtime.NumCPU()*4, "maximum number of requests inspected at the same time")
+	flags.Var((*originsFlag)(&cfg.origins), "origin", "origin allowed for ?url= (repeatable)")
+	flags.DurationVar(&cfg.fetchTimeout, "fetch-timeout", 10*time.Second, "timeout of fetching ?url=")
+	if err := flags.Parse(args); err != nil {
+		return "", cfg, err
+	}
+	if flags.NArg() > 0 {
+		return "", cfg, fmt.Errorf("unexpected argument: %s", flags.Arg(0))
+	}
+	if cfg.maxBody <= 0 || cfg.maxConcurrent <= 0 {
+		return "", cfg, fmt.Errorf("--max-body and --max-concurrent must be positive")
+	}
+	return *addr, cfg, nil
+}
+
+func main() {
+	addr, cfg, err := parseFlags(os.Args[1:], os.Stderr)
+	if err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(os.Stderr, "midecd:", err)
+		}
+		os.Exit(2)
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           newServer(cfg),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("midecd: listening on %s", addr)
+	log.Fatal(srv.ListenAndServe())
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sapphi-red/midec"
+	"github.com/sapphi-red/midec/httpmw"
+	"github.com/sapphi-red/midec/scan"
+)
+
+var (
+	errEmptyBody        = errors.New("empty body")
+	errOriginNotAllowed = errors.New("origin not allowed")
+	errBusy             = errors.New("too many requests in flight")
+)
+
+// config configures server.
+type config struct {
+	// maxBody is the maximum size of a body or a fetched object.
+	maxBody int64
+	// maxConcurrent is the maximum number of inspections at the same time.
+	maxConcurrent int
+	// origins is the allow-list of the origins (scheme://host[:port]) fetched by ?url=.
+	origins []string
+	// fetchTimeout is the timeout of fetching ?url=.
+	fetchTimeout time.Duration
+}
+
+// result is the JSON of an inspected image.
+type result struct {
+	// Field and Filename are set for a part of a multipart body.
+	Field    string       `json:"field,omitempty"`
+	Filename string       `json:"filename,omitempty"`
+	Verdict  scan.Verdict `json:"verdict"`
+	Format   string       `json:"format,omitempty"`
+	Kind     string       `json:"kind,omitempty"`
+	Animated bool         `json:"animated"`
+	Frames   int          `json:"frames"`
+	Loops    int          `json:"loops"`
+	Width    int          `json:"width"`
+	Height   int          `json:"height"`
+	Duration float64      `json:"duration"` // seconds
+	Error    string       `json:"error,omitempty"`
+}
+
+func newResult(info *midec.Info, err error) result {
+	res := result{Verdict: scan.Classify(info, err)}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	res.Format = info.Format
+	res.Kind = info.Kind.String()
+	res.Animated = info.Kind.IsMultiImage()
+	res.Frames = info.Frames
+	res.Loops = info.Loops
+	res.Width = info.Width
+	res.Height = info.Height
+	res.Duration = info.Duration.Seconds()
+	return res
+}
+
+type inspectionKey struct {
+	format  string
+	verdict scan.Verdict
+}
+
+// metrics is exposed in the Prometheus text format.
+type metrics struct {
+	mu          sync.Mutex
+	requests    map[int]uint64
+	inspections map[inspectionKey]uint64
+	inFlight    int
+	// durationSum is the total time taken by /inspect in seconds.
+	durationSum float64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests:    make(map[int]uint64),
+		inspections: make(map[inspectionKey]uint64),
+	}
+}
+
+func (m *metrics) addInFlight(delta int) {
+	m.mu.Lock()
+	m.inFlight += delta
+	m.mu.Unlock()
+}
+
+func (m *metrics) observeRequest(code int, duration time.Duration) {
+	m.mu.Lock()
+	m.requests[code]++
+	m.durationSum += duration.Seconds()
+	m.mu.Unlock()
+}
+
+func (m *metrics) observeResult(res result) {
+	m.mu.Lock()
+	m.inspections[inspectionKey{res.Format, res.Verdict}]++
+	m.mu.Unlock()
+}
+
+func (m *metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var buf bytes.Buffer
+
+	codes := make([]int, 0, len(m.requests))
+	total := uint64(0)
+	for code, count := range m.requests {
+		codes = append(codes, code)
+		total += count
+	}
+	sort.Ints(codes)
+	fmt.Fprintln(&buf, "# HELP midecd_requests_total Number of /inspect requests by status code.")
+	fmt.Fprintln(&buf, "# TYPE midecd_requests_total counter")
+	for _, code := range codes {
+		fmt.Fprintf(&buf, "midecd_requests_total{code=\"%d\"} %d\n", code, m.requests[code])
+	}
+
+	fmt.Fprintln(&buf, "# HELP midecd_request_duration_seconds Time taken by /inspect requests.")
+	fmt.Fprintln(&buf, "# TYPE midecd_request_duration_seconds summary")
+	fmt.Fprintf(&buf, "midecd_request_duration_seconds_sum %g\n", m.durationSum)
+	fmt.Fprintf(&buf, "midecd_request_duration_seconds_count %d\n", total)
+
+	keys := make([]inspectionKey, 0, len(m.inspections))
+	for key := range m.inspections {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].format != keys[j].format {
+			return keys[i].format < keys[j].format
+		}
+		return keys[i].verdict < keys[j].verdict
+	})
+	fmt.Fprintln(&buf, "# HELP midecd_inspections_total Number of inspected images by format and verdict.")
+	fmt.Fprintln(&buf, "# TYPE midecd_inspections_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "midecd_inspections_total{format=%q,verdict=%q} %d\n", key.format, key.verdict, m.inspections[key])
+	}
+
+	fmt.Fprintln(&buf, "# HELP midecd_in_flight_requests Number of /inspect requests being processed.")
+	fmt.Fprintln(&buf, "# TYPE midecd_in_flight_requests gauge")
+	fmt.Fprintf(&buf, "midecd_in_flight_requests %d\n", m.inFlight)
+
+	return buf.WriteTo(w)
+}
+
+type server struct {
+	cfg     config
+	sem     chan struct{}
+	metrics *metrics
+	client  *http.Client
+	// inspectBody inspects a raw or multipart body with httpmw.
+	inspectBody http.Handler
+	mux         *http.ServeMux
+}
+
+func newServer(cfg config) *server {
+	s := &server{
+		cfg:     cfg,
+		sem:     make(chan struct{}, cfg.maxConcurrent),
+		metrics: newMetrics(),
+	}
+	s.client = &http.Client{
+		Timeout: cfg.fetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !s.isAllowedOrigin(req.URL) {
+				return errOriginNotAllowed
+			}
+			return nil
+		},
+	}
+	s.inspectBody = httpmw.Middleware(httpmw.Options{
+		Policy:    httpmw.Policy{AllowAnimated: true},
+		TagOnly:   true,
+		MaxMemory: cfg.maxBody,
+	})(http.HandlerFunc(s.writeBodyResults))
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/inspect", s.handleInspect)
+	s.mux.HandleFunc("/metrics", s.handleMetrics)
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *server) isAllowedOrigin(u *url.URL) bool {
+	if u.User != nil {
+		return false
+	}
+	origin := strings.ToLower(u.Scheme + "://" + u.Host)
+	for _, allowed := range s.cfg.origins {
+		if origin == strings.ToLower(strings.TrimSuffix(allowed, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// statusWriter records the status code for the metrics.
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	sw.code = code
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, result{Error: err.Error()})
+}
+
+func (s *server) handleInspect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	start := time.Now()
+	sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+	defer func() {
+		s.metrics.observeRequest(sw.code, time.Since(start))
+	}()
+
+	select {
+	case s.sem <- struct{}{}:
+		defer func() { <-s.sem }()
+	default:
+		sw.Header().Set("Retry-After", "1")
+		writeError(sw, http.StatusServiceUnavailable, errBusy)
+		return
+	}
+	s.metrics.addInFlight(1)
+	defer s.metrics.addInFlight(-1)
+
+	if rawURL := r.URL.Query().Get("url"); rawURL != "" {
+		s.inspectURL(sw, r, rawURL)
+		return
+	}
+	s.inspectBody.ServeHTTP(sw, r)
+}
+
+func (s *server) writeBodyResults(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	isMultipart := mediaType == "multipart/form-data"
+
+	mwResults := httpmw.ResultsFromContext(r.Context())
+	if len(mwResults) == 0 && !isMultipart {
+		writeError(w, http.StatusBadRequest, errEmptyBody)
+		return
+	}
+
+	results := make([]result, 0, len(mwResults))
+	for _, mwRes := range mwResults {
+		// errors on the body itself, not on the image
+		if errors.Is(mwRes.Err, httpmw.ErrBodyTooLarge) || errors.Is(mwRes.Err, httpmw.ErrInvalidMultipart) {
+			writeError(w, mwRes.StatusCode(), mwRes.Err)
+			return
+		}
+
+		res := newResult(mwRes.Info, mwRes.Err)
+		res.Field, res.Filename = mwRes.FieldName, mwRes.FileName
+		s.metrics.observeResult(res)
+		results = append(results, res)
+	}
+
+	if isMultipart {
+		writeJSON(w, http.StatusOK, results)
+		return
+	}
+	writeJSON(w, http.StatusOK, results[0])
+}
+
+func (s *server) inspectURL(w http.ResponseWriter, r *http.Request, rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !s.isAllowedOrigin(u) {
+		writeError(w, http.StatusForbidden, errOriginNotAllowed)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, u.String(), nil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("origin responded %s", resp.Status))
+		return
+	}
+
+	// the whole object is read since some formats need io.Seeker
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, s.cfg.maxBody+1))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if int64(len(b)) > s.cfg.maxBody {
+		writeError(w, http.StatusRequestEntityTooLarge, httpmw.ErrBodyTooLarge)
+		return
+	}
+
+	res := newResult(midec.Inspect(bytes.NewReader(b)))
+	s.metrics.observeResult(res)
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = s.metrics.WriteTo(w)
+}
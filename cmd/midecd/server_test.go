@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testdataFolder = "../../testdata/"
+
+func readTestdata(filename string) []byte {
+	b, err := os.ReadFile(testdataFolder + filename)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var (
+	animatedGIF = result{Verdict: "animated", Format: "gif", Kind: "animated", Animated: true, Frames: 26, Loops: 1, Width: 242, Height: 175, Duration: 1.98}
+	staticPNG   = result{Verdict: "static", Format: "png", Kind: "static", Frames: 1, Width: 242, Height: 175}
+)
+
+// newOrigin serves the testdata, and redirects /redirect to another origin.
+func newOrigin() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir(testdataFolder)))
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://example.com/gif/animated.gif", http.StatusFound)
+	})
+	return httptest.NewServer(mux)
+}
+
+func newTestConfig(origin string) config {
+	return config{
+		maxBody:       1 << 20,
+		maxConcurrent: 2,
+		origins:       []string{origin},
+		fetchTimeout:  5 * time.Second,
+	}
+}
+
+func newMultipartBody() (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, filename := range []string{"gif/animated.gif", "png/static.png"} {
+		w, err := mw.CreateFormFile("image", path.Base(filename))
+		if err != nil {
+			panic(err)
+		}
+		if _, err := w.Write(readTestdata(filename)); err != nil {
+			panic(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		panic(err)
+	}
+	return &body, mw.FormDataContentType()
+}
+
+func Test_server_Inspect(t *testing.T) {
+	t.Parallel()
+
+	origin := newOrigin()
+	// the subtests run after returning
+	t.Cleanup(origin.Close)
+
+	multipartBody, contentType := newMultipartBody()
+	multipartAnimated, multipartStatic := animatedGIF, staticPNG
+	multipartAnimated.Field, multipartAnimated.Filename = "image", "animated.gif"
+	multipartStatic.Field, multipartStatic.Filename = "image", "static.png"
+
+	testcases := []struct {
+		name           string
+		method         string
+		target         string
+		contentType    string
+		body           []byte
+		maxBody        int64
+		expectedStatus int
+		expected       interface{}
+	}{
+		{"raw", http.MethodPost, "/inspect", "image/gif", readTestdata("gif/animated.gif"), 0, http.StatusOK, animatedGIF},
+		{
+			"multipart", http.MethodPost, "/inspect", contentType, multipartBody.Bytes(), 0, http.StatusOK,
+			[]result{multipartAnimated, multipartStatic},
+		},
+		{
+			"unknown format", http.MethodPost, "/inspect", "", []byte("not an image"), 0, http.StatusOK,
+			result{Verdict: "unknown", Error: "midec: unknown format"},
+		},
+		{"empty body", http.MethodPost, "/inspect", "", nil, 0, http.StatusBadRequest, result{Error: errEmptyBody.Error()}},
+		{"method", http.MethodGet, "/inspect", "", nil, 0, http.StatusMethodNotAllowed, result{Error: "method not allowed"}},
+		{
+			"too large", http.MethodPost, "/inspect", "", readTestdata("gif/animated.gif"), 10000, http.StatusRequestEntityTooLarge,
+			result{Error: "midec: (httpmw) body too large"},
+		},
+		{"url", http.MethodPost, "/inspect?url=" + url.QueryEscape(origin.URL+"/png/static.png"), "", nil, 0, http.StatusOK, staticPNG},
+		{
+			"url too large", http.MethodPost, "/inspect?url=" + url.QueryEscape(origin.URL+"/gif/animated.gif"), "", nil, 10000, http.StatusRequestEntityTooLarge,
+			result{Error: "midec: (httpmw) body too large"},
+		},
+		{
+			"url not allowed", http.MethodPost, "/inspect?url=" + url.QueryEscape("http://example.com/animated.gif"), "", nil, 0, http.StatusForbidden,
+			result{Error: errOriginNotAllowed.Error()},
+		},
+		{
+			"url with userinfo", http.MethodPost, "/inspect?url=" + url.QueryEscape(strings.Replace(origin.URL, "://", "://user@", 1)+"/png/static.png"), "", nil, 0, http.StatusForbidden,
+			result{Error: errOriginNotAllowed.Error()},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := newTestConfig(origin.URL)
+			if tc.maxBody > 0 {
+				cfg.maxBody = tc.maxBody
+			}
+			s := newServer(cfg)
+
+			r := httptest.NewRequest(tc.method, tc.target, bytes.NewReader(tc.body))
+			if tc.body == nil {
+				r.Body = http.NoBody
+			}
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, r)
+
+			if rec.Code != tc.expectedStatus {
+				t.Errorf("StatusCode = %d; want %d (body: %s)", rec.Code, tc.expectedStatus, rec.Body.String())
+			}
+
+			actual := reflect.New(reflect.TypeOf(tc.expected))
+			if err := json.Unmarshal(rec.Body.Bytes(), actual.Interface()); err != nil {
+				t.Fatalf("Error = %v; want HasError = false", err)
+			}
+			if !reflect.DeepEqual(actual.Elem().Interface(), tc.expected) {
+				t.Errorf("Result = %+v; want %+v", actual.Elem().Interface(), tc.expected)
+			}
+		})
+	}
+}
+
+func Test_server_InspectURL_BadGateway(t *testing.T) {
+	t.Parallel()
+
+	origin := newOrigin()
+	defer origin.Close()
+
+	for _, p := range []string{"/notfound.gif", "/redirect"} {
+		s := newServer(newTestConfig(origin.URL))
+		r := httptest.NewRequest(http.MethodPost, "/inspect?url="+url.QueryEscape(origin.URL+p), http.NoBody)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, r)
+
+		if rec.Code != http.StatusBadGateway {
+			t.Errorf("%s: StatusCode = %d; want %d", p, rec.Code, http.StatusBadGateway)
+		}
+	}
+}
+
+func Test_server_Busy(t *testing.T) {
+	t.Parallel()
+
+	s := newServer(newTestConfig(""))
+	// fill the slots as if other requests were in flight
+	for i := 0; i < cap(s.sem); i++ {
+		s.sem <- struct{}{}
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/inspect", bytes.NewReader(readTestdata("png/static.png")))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, r)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("StatusCode = %d; want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func Test_server_Metrics(t *testing.T) {
+	t.Parallel()
+
+	s := newServer(newTestConfig(""))
+	animated, static := readTestdata("gif/animated.gif"), readTestdata("png/static.png")
+	for _, body := range [][]byte{animated, animated, static, []byte("not an image")} {
+		r := httptest.NewRequest(http.MethodPost, "/inspect", bytes.NewReader(body))
+		s.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/inspect", http.NoBody))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	for _, expected := range []string{
+		"midecd_requests_total{code=\"200\"} 4\n",
+		"midecd_requests_total{code=\"400\"} 1\n",
+		"midecd_request_duration_seconds_count 5\n",
+		"midecd_inspections_total{format=\"\",verdict=\"unknown\"} 1\n",
+		"midecd_inspections_total{format=\"gif\",verdict=\"animated\"} 2\n",
+		"midecd_inspections_total{format=\"png\",verdict=\"static\"} 1\n",
+		"midecd_in_flight_requests 0\n",
+	} {
+		if !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("Metrics = %q; want to contain %q", rec.Body.String(), expected)
+		}
+	}
+}
+
+func Test_parseFlags(t *testing.T) {
+	t.Parallel()
+
+	var stderr bytes.Buffer
+	addr, cfg, err := parseFlags([]string{"--addr", ":9000", "--origin", "http://a", "--origin", "http://b:8080"}, &stderr)
+	if err != nil {
+		t.Fatalf("Error = %v; want HasError = false", err)
+	}
+	if addr != ":9000" {
+		t.Errorf("Addr = %s; want :9000", addr)
+	}
+	if !reflect.DeepEqual(cfg.origins, []string{"http://a", "http://b:8080"}) {
+		t.Errorf("Origins = %v; want [http://a http://b:8080]", cfg.origins)
+	}
+
+	if _, _, err := parseFlags([]string{"--max-concurrent", "0"}, &stderr); err == nil {
+		t.Errorf("Error = nil; want HasError = true")
+	}
+}
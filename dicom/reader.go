@@ -0,0 +1,338 @@
+// Package dicom implements a multi-frame DICOM detector
+package dicom
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sapphi-red/midec"
+)
+
+// the magic is after the 128-byte preamble
+var dicomHeader = strings.Repeat("?", preambleSize) + "DICM"
+
+// ErrInvalidElement indicates that detecting encountered a broken data element.
+var ErrInvalidElement = errors.New("midec: (dicom) invalid data element")
+
+// ErrUnsupportedTransferSyntax indicates that the data set was not little-endian or was deflated.
+var ErrUnsupportedTransferSyntax = errors.New("midec: (dicom) unsupported transfer syntax")
+
+const preambleSize = 128
+
+const (
+	transferSyntaxImplicitVRLittleEndian = "1.2.840.10008.1.2"
+	transferSyntaxExplicitVRBigEndian    = "1.2.840.10008.1.2.2"
+	transferSyntaxDeflated               = "1.2.840.10008.1.2.1.99"
+)
+
+type tag uint32
+
+func newTag(group, element uint16) tag {
+	return tag(uint32(group)<<16 | uint32(element))
+}
+
+func (t tag) group() uint16 {
+	return uint16(t >> 16)
+}
+
+const (
+	tagFileMetaInformationGroupLength tag = 0x00020000
+	tagTransferSyntaxUID              tag = 0x00020010
+	tagCineRate                       tag = 0x00180040
+	tagFrameTime                      tag = 0x00181063
+	tagNumberOfFrames                 tag = 0x00280008
+
+	tagItemDelimitation         tag = 0xfffee00d
+	tagSequenceDelimitationItem tag = 0xfffee0dd
+
+	groupItem       = 0xfffe
+	groupPixelData  = 0x7fe0
+	undefinedLength = 0xffffffff
+)
+
+// maxValueSize is the maximum size of a value to be read into memory.
+const maxValueSize = 256
+
+// Object is the information read from the data set.
+type Object struct {
+	TransferSyntaxUID string
+	// NumberOfFrames is 1 when the data set does not have it.
+	NumberOfFrames int
+	// FrameTime is the nominal time per frame. It is 0 when the data set does not have it.
+	FrameTime time.Duration
+	// CineRate is the number of frames per second. It is 0 when the data set does not have it.
+	CineRate int
+}
+
+// IsCine reports whether the frames are meant to be played as a cine loop.
+func (o *Object) IsCine() bool {
+	return o.NumberOfFrames >= 2 && (o.FrameTime > 0 || o.CineRate > 0)
+}
+
+// Duration returns the time taken by one cycle of a cine loop.
+func (o *Object) Duration() time.Duration {
+	if o.FrameTime > 0 {
+		return time.Duration(o.NumberOfFrames) * o.FrameTime
+	}
+	if o.CineRate > 0 {
+		return time.Duration(o.NumberOfFrames) * time.Second / time.Duration(o.CineRate)
+	}
+	return 0
+}
+
+type elementHeaderData struct {
+	tag    tag
+	length uint32
+}
+
+type decoder struct {
+	midec.ReadAdvancer
+	implicitVR bool
+}
+
+func (d *decoder) read(data interface{}) error {
+	return d.ReadBinary(binary.LittleEndian, data)
+}
+
+// hasLongLength reports whether the VR has 2 reserved bytes and a 32-bit length in explicit VR.
+func hasLongLength(vr string) bool {
+	switch vr {
+	case "OB", "OD", "OF", "OL", "OV", "OW", "SQ", "SV", "UC", "UN", "UR", "UT", "UV":
+		return true
+	}
+	return false
+}
+
+func (d *decoder) decodeElementHeader() (ehd elementHeaderData, err error) {
+	var group, element uint16
+	if err = d.read(&group); err != nil {
+		return
+	}
+	if err = d.read(&element); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	ehd.tag = newTag(group, element)
+
+	// items and delimiters do not have VR
+	if d.implicitVR || group == groupItem {
+		err = d.read(&ehd.length)
+		return
+	}
+
+	vr, err := d.Next(2)
+	if err != nil {
+		return
+	}
+	if !hasLongLength(string(vr)) {
+		var length uint16
+		err = d.read(&length)
+		ehd.length = uint32(length)
+		return
+	}
+
+	err = d.Advance(
+		2, // reserved
+	)
+	if err != nil {
+		return
+	}
+	err = d.read(&ehd.length)
+	return
+}
+
+func (d *decoder) readString(ehd elementHeaderData) (string, error) {
+	if ehd.length > maxValueSize {
+		return "", ErrInvalidElement
+	}
+
+	buf := make([]byte, ehd.length)
+	if _, err := d.ReadFull(buf); err != nil {
+		return "", err
+	}
+	return strings.Trim(string(buf), " \x00"), nil
+}
+
+func (d *decoder) readNumber(ehd elementHeaderData) (float64, error) {
+	s, err := d.readString(ehd)
+	if err != nil {
+		return 0, err
+	}
+	// only the first value is used when multi-valued
+	if i := strings.IndexByte(s, '\\'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, ErrInvalidElement
+	}
+	return v, nil
+}
+
+// skipUndefinedLength skips a sequence or an item of undefined length, including the nested ones.
+func (d *decoder) skipUndefinedLength() error {
+	depth := 1
+	for depth > 0 {
+		ehd, err := d.decodeElementHeader()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		switch {
+		case ehd.tag == tagItemDelimitation, ehd.tag == tagSequenceDelimitationItem:
+			depth--
+		case ehd.length == undefinedLength:
+			depth++
+		default:
+			if err := d.Advance(uint(ehd.length)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *decoder) skipElement(ehd elementHeaderData) error {
+	if ehd.length == undefinedLength {
+		return d.skipUndefinedLength()
+	}
+	return d.Advance(uint(ehd.length))
+}
+
+// decodeFileMeta reads the File Meta Information and returns the Transfer Syntax UID.
+func (d *decoder) decodeFileMeta() (string, error) {
+	err := d.Advance(
+		preambleSize +
+			4, // 'DICM'
+	)
+	if err != nil {
+		return "", err
+	}
+
+	ehd, err := d.decodeElementHeader()
+	if err != nil {
+		return "", err
+	}
+	if ehd.tag != tagFileMetaInformationGroupLength || ehd.length != 4 {
+		return "", ErrInvalidElement
+	}
+	var groupLength uint32
+	if err := d.read(&groupLength); err != nil {
+		return "", err
+	}
+
+	metaEnd := d.Offset() + int64(groupLength)
+	transferSyntax := ""
+	for d.Offset() < metaEnd {
+		ehd, err := d.decodeElementHeader()
+		if err != nil {
+			return "", err
+		}
+
+		if ehd.tag == tagTransferSyntaxUID {
+			if transferSyntax, err = d.readString(ehd); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err := d.skipElement(ehd); err != nil {
+			return "", err
+		}
+	}
+	return transferSyntax, nil
+}
+
+func (d *decoder) decodeObject() (*Object, error) {
+	transferSyntax, err := d.decodeFileMeta()
+	if err != nil {
+		return nil, err
+	}
+
+	switch transferSyntax {
+	case transferSyntaxExplicitVRBigEndian, transferSyntaxDeflated:
+		return nil, ErrUnsupportedTransferSyntax
+	case transferSyntaxImplicitVRLittleEndian:
+		d.implicitVR = true
+	}
+
+	o := &Object{TransferSyntaxUID: transferSyntax, NumberOfFrames: 1}
+	for {
+		ehd, err := d.decodeElementHeader()
+		if err != nil {
+			if err == io.EOF {
+				return o, nil
+			}
+			return nil, err
+		}
+		if ehd.tag.group() >= groupPixelData {
+			return o, nil
+		}
+
+		var v float64
+		switch ehd.tag {
+		case tagNumberOfFrames:
+			v, err = d.readNumber(ehd)
+			o.NumberOfFrames = int(v)
+		case tagFrameTime:
+			v, err = d.readNumber(ehd)
+			o.FrameTime = time.Duration(math.Round(v * float64(time.Millisecond)))
+		case tagCineRate:
+			v, err = d.readNumber(ehd)
+			o.CineRate = int(v)
+		default:
+			err = d.skipElement(ehd)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (d *decoder) inspect() (*midec.Info, error) {
+	o, err := d.decodeObject()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case o.IsCine():
+		return &midec.Info{Kind: midec.KindAnimated, Frames: o.NumberOfFrames, Duration: o.Duration()}, nil
+	case o.NumberOfFrames >= 2:
+		return &midec.Info{Kind: midec.KindMultiImage, Frames: o.NumberOfFrames}, nil
+	}
+	return &midec.Info{Kind: midec.KindStatic, Frames: o.NumberOfFrames}, nil
+}
+
+// DecodeObject reads the data elements up to Pixel Data.
+func DecodeObject(r io.Reader) (*Object, error) {
+	d := decoder{ReadAdvancer: *midec.NewReadAdvancer(r)}
+	return d.decodeObject()
+}
+
+func isAnimated(r io.Reader) (bool, error) {
+	info, err := inspect(r)
+	if err != nil {
+		return false, err
+	}
+	return info.Kind.IsMultiImage(), nil
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{ReadAdvancer: *midec.NewReadAdvancer(r)}
+	return d.inspect()
+}
+
+func init() {
+	midec.RegisterInspectableFormat("dicom", dicomHeader, isAnimated, inspect)
+}
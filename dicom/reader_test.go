@@ -0,0 +1,92 @@
+package dicom
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testdataFolder = "../testdata/dicom/"
+
+func Test_isAnimated(t *testing.T) {
+	t.Parallel()
+
+	runIsAnimated := func(filename string) (bool, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return isAnimated(fp)
+	}
+
+	testcases := []struct {
+		filename           string
+		expectedIsAnimated bool
+		expectedHasError   bool
+	}{
+		{"cine.dcm", true, false},
+		{"cine-implicit.dcm", true, false},
+		{"multiframe.dcm", true, false},
+		{"static.dcm", false, false},
+		{"invalid-bigendian.dcm", false, true},
+		{"invalid-number.dcm", false, true},
+		{"invalid-element.dcm", false, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actualIsAnimated, actualErr := runIsAnimated(tc.filename)
+			if tc.expectedIsAnimated != actualIsAnimated {
+				t.Errorf("IsAnimated = %t; want %t", actualIsAnimated, tc.expectedIsAnimated)
+			}
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+		})
+	}
+}
+
+func Test_DecodeObject(t *testing.T) {
+	t.Parallel()
+
+	runDecodeObject := func(filename string) (*Object, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return DecodeObject(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expected         *Object
+		expectedDuration time.Duration
+	}{
+		{"cine.dcm", &Object{TransferSyntaxUID: "1.2.840.10008.1.2.1", NumberOfFrames: 30, FrameTime: 33300 * time.Microsecond}, 999 * time.Millisecond},
+		{"cine-implicit.dcm", &Object{TransferSyntaxUID: "1.2.840.10008.1.2", NumberOfFrames: 10, CineRate: 25}, 400 * time.Millisecond},
+		{"multiframe.dcm", &Object{TransferSyntaxUID: "1.2.840.10008.1.2.1", NumberOfFrames: 12}, 0},
+		{"static.dcm", &Object{TransferSyntaxUID: "1.2.840.10008.1.2.1", NumberOfFrames: 1}, 0},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runDecodeObject(tc.filename)
+			if actualErr != nil {
+				t.Fatalf("Error = %v; want HasError = false", actualErr)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Object = %+v; want %+v", actual, tc.expected)
+			}
+			if actual.Duration() != tc.expectedDuration {
+				t.Errorf("Duration = %v; want %v", actual.Duration(), tc.expectedDuration)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Package djvu implements a multi-page DjVu detector
+package djvu
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/sapphi-red/midec"
+	"github.com/sapphi-red/midec/internal/riff"
+)
+
+const (
+	singlePageHeader = "AT&TFORM????DJVU"
+	multiPageHeader  = "AT&TFORM????DJVM"
+)
+
+// ErrNoDirectory indicates that DIRM chunk was not found in a multi-page document.
+var ErrNoDirectory = errors.New("midec: (djvu) DIRM chunk not found")
+
+// ErrInvalidComponent indicates that an offset in DIRM chunk did not point to a FORM chunk.
+var ErrInvalidComponent = errors.New("midec: (djvu) invalid component offset")
+
+const (
+	formTypePage     = "DJVU"
+	formTypeDocument = "DJVM"
+
+	maskBundled = 0x80
+)
+
+// Document is the information read from the DIRM chunk.
+type Document struct {
+	// IsBundled is false for an indirect document, whose components are separate files.
+	IsBundled bool
+	// Components is the number of component files, including shared data and thumbnails.
+	Components int
+	// Pages is the number of pages.
+	// It is 0 for an indirect document since the component types are only stored compressed.
+	Pages int
+}
+
+// isMultiPage reports whether the document has two or more pages.
+// An indirect document is always regarded as multi-page, since its pages cannot be told from
+// the other components, such as shared data, without decompressing the directory.
+func (doc *Document) isMultiPage() bool {
+	if !doc.IsBundled {
+		return true
+	}
+	return doc.Pages >= 2
+}
+
+type dirmData struct {
+	isBundled bool
+	offsets   []uint32
+	count     int
+}
+
+type decoder struct {
+	riff.Decoder
+}
+
+// decodeHeader reads the magic and the FORM header and returns the form type.
+func (d *decoder) decodeHeader() (string, error) {
+	err := d.Advance(
+		4, // 'AT&T'
+	)
+	if err != nil {
+		return "", err
+	}
+	return d.DecodeFormHeader()
+}
+
+func (d *decoder) decodeDirectoryChunk(chd riff.ChunkHeaderData) (dd dirmData, err error) {
+	var header struct {
+		Flags  uint8
+		NFiles uint16
+	}
+	if chd.DataSize < 3 {
+		err = ErrNoDirectory
+		return
+	}
+	if err = binary.Read(d, d.ByteOrder, &header); err != nil {
+		return
+	}
+
+	dd.isBundled = header.Flags&maskBundled != 0
+	dd.count = int(header.NFiles)
+	if !dd.isBundled {
+		return
+	}
+
+	if chd.DataSize < 3+4*uint32(header.NFiles) {
+		err = ErrNoDirectory
+		return
+	}
+	dd.offsets = make([]uint32, header.NFiles)
+	for i := range dd.offsets {
+		if dd.offsets[i], err = d.ReadUint32(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// decodeComponentType reads the form type of the component at offset.
+func (d *decoder) decodeComponentType(offset uint32) (string, error) {
+	if err := d.SeekTo(int64(offset)); err != nil {
+		return "", err
+	}
+
+	chd, err := d.DecodeChunkHeader()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	if chd.FourCC != "FORM" {
+		return "", ErrInvalidComponent
+	}
+	return d.ReadFourCC()
+}
+
+func (d *decoder) decodeDocument() (*Document, error) {
+	formType, err := d.decodeHeader()
+	if err != nil {
+		return nil, err
+	}
+	if formType == formTypePage {
+		return &Document{IsBundled: true, Components: 1, Pages: 1}, nil
+	}
+	if formType != formTypeDocument {
+		return nil, ErrNoDirectory
+	}
+
+	// DIRM chunk must be the first chunk
+	chd, err := d.DecodeChunkHeader()
+	if err != nil {
+		if err == io.EOF {
+			err = ErrNoDirectory
+		}
+		return nil, err
+	}
+	if chd.FourCC != "DIRM" {
+		return nil, ErrNoDirectory
+	}
+
+	dd, err := d.decodeDirectoryChunk(chd)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{IsBundled: dd.isBundled, Components: dd.count}
+	// offsets are in ascending order, so it does not go backward
+	for _, offset := range dd.offsets {
+		componentType, err := d.decodeComponentType(offset)
+		if err != nil {
+			return nil, err
+		}
+		if componentType == formTypePage {
+			doc.Pages++
+		}
+	}
+	return doc, nil
+}
+
+func (d *decoder) inspect() (*midec.Info, error) {
+	doc, err := d.decodeDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	// the number of pages is only reported for a bundled document, Pages is 0 otherwise
+	if doc.isMultiPage() {
+		return &midec.Info{Kind: midec.KindMultiImage, Frames: doc.Pages}, nil
+	}
+	return &midec.Info{Kind: midec.KindStatic, Frames: doc.Pages}, nil
+}
+
+// DecodeDocument reads DIRM chunk and counts the pages.
+func DecodeDocument(r io.Reader) (*Document, error) {
+	d := decoder{*riff.NewDecoder(r, binary.BigEndian)}
+	return d.decodeDocument()
+}
+
+func isAnimated(r io.Reader) (bool, error) {
+	info, err := inspect(r)
+	if err != nil {
+		return false, err
+	}
+	return info.Kind.IsMultiImage(), nil
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{*riff.NewDecoder(r, binary.BigEndian)}
+	return d.inspect()
+}
+
+func init() {
+	midec.RegisterInspectableFormat("djvu", singlePageHeader, isAnimated, inspect)
+	midec.RegisterInspectableFormat("djvu", multiPageHeader, isAnimated, inspect)
+}
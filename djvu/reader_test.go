@@ -0,0 +1,133 @@
+package djvu
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sapphi-red/midec"
+)
+
+const testdataFolder = "../testdata/djvu/"
+
+func Test_isAnimated(t *testing.T) {
+	t.Parallel()
+
+	runIsAnimated := func(filename string) (bool, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return isAnimated(fp)
+	}
+
+	testcases := []struct {
+		filename           string
+		expectedIsAnimated bool
+		expectedHasError   bool
+	}{
+		{"multipage.djvu", true, false},
+		{"single-bundled.djvu", false, false},
+		{"single.djvu", false, false},
+		{"indirect.djvu", true, false},
+		{"invalid-nodirm.djvu", false, true},
+		{"invalid-offset.djvu", false, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actualIsAnimated, actualErr := runIsAnimated(tc.filename)
+			if tc.expectedIsAnimated != actualIsAnimated {
+				t.Errorf("IsAnimated = %t; want %t", actualIsAnimated, tc.expectedIsAnimated)
+			}
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+		})
+	}
+}
+
+func Test_DecodeDocument(t *testing.T) {
+	t.Parallel()
+
+	runDecodeDocument := func(filename string) (*Document, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return DecodeDocument(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expected         *Document
+		expectedHasError bool
+	}{
+		{"multipage.djvu", &Document{IsBundled: true, Components: 4, Pages: 3}, false},
+		{"single-bundled.djvu", &Document{IsBundled: true, Components: 2, Pages: 1}, false},
+		{"single.djvu", &Document{IsBundled: true, Components: 1, Pages: 1}, false},
+		{"indirect.djvu", &Document{IsBundled: false, Components: 3}, false},
+		{"invalid-nodirm.djvu", nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runDecodeDocument(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Document = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_inspect(t *testing.T) {
+	t.Parallel()
+
+	runInspect := func(filename string) (*midec.Info, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return inspect(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expected         *midec.Info
+		expectedHasError bool
+	}{
+		{"multipage.djvu", &midec.Info{Kind: midec.KindMultiImage, Frames: 3}, false},
+		{"single-bundled.djvu", &midec.Info{Kind: midec.KindStatic, Frames: 1}, false},
+		// the components are not counted as pages
+		{"indirect.djvu", &midec.Info{Kind: midec.KindMultiImage}, false},
+		{"invalid-offset.djvu", nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runInspect(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Info = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// Package flic implements an Autodesk FLI / FLC animation detector
+package flic
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/sapphi-red/midec"
+)
+
+// ErrInvalidHeader indicates that the header was broken.
+var ErrInvalidHeader = errors.New("midec: (flic) invalid header")
+
+// ErrInvalidChunk indicates that detecting encountered a broken chunk.
+var ErrInvalidChunk = errors.New("midec: (flic) invalid chunk")
+
+// ErrFrameCountMismatch indicates that the file had fewer frame chunks than declared in the header.
+var ErrFrameCountMismatch = errors.New("midec: (flic) fewer frames than declared")
+
+const (
+	magicFLI = 0xaf11
+	magicFLC = 0xaf12
+)
+
+const (
+	headerSize      = 128
+	chunkHeaderSize = 6
+
+	chunkTypePrefix = 0xf100
+	chunkTypeFrame  = 0xf1fa
+)
+
+// fliJiffiesPerSecond is the number of jiffies, the unit of FLI speed, in a second.
+const fliJiffiesPerSecond = 70
+
+// Animation is the information read from the FLI / FLC header.
+type Animation struct {
+	// IsFLC is true for FLC, false for FLI.
+	IsFLC  bool
+	Width  int
+	Height int
+	// Frames is the number of frames declared in the header. It does not include the ring frame.
+	Frames int
+	// Speed is the delay between frames, in jiffies (1/70 sec) for FLI and in milliseconds for FLC.
+	Speed uint32
+}
+
+// Delay returns the delay between frames.
+func (a *Animation) Delay() time.Duration {
+	if a.IsFLC {
+		return time.Duration(a.Speed) * time.Millisecond
+	}
+	return time.Duration(a.Speed) * time.Second / fliJiffiesPerSecond
+}
+
+type chunkHeaderData struct {
+	size      uint32
+	chunkType uint16
+}
+
+type decoder struct {
+	midec.ReadAdvancer
+}
+
+func (d *decoder) read(data interface{}) error {
+	return d.ReadBinary(binary.LittleEndian, data)
+}
+
+// decodeHeader reads the header and returns the offset of the first frame.
+func (d *decoder) decodeHeader() (*Animation, int64, error) {
+	var header struct {
+		Size    uint32
+		Magic   uint16
+		Frames  uint16
+		Width   uint16
+		Height  uint16
+		Depth   uint16
+		Flags   uint16
+		Speed   uint32
+		_       [60]byte
+		OFrame1 uint32
+		OFrame2 uint32
+		_       [40]byte
+	}
+	if err := d.read(&header); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+
+	a := &Animation{
+		Width:  int(header.Width),
+		Height: int(header.Height),
+		Frames: int(header.Frames),
+		Speed:  header.Speed,
+	}
+	firstFrame := int64(headerSize)
+	switch header.Magic {
+	case magicFLI:
+		// FLI speed is 16 bits
+		a.Speed &= 0xffff
+	case magicFLC:
+		a.IsFLC = true
+		if header.OFrame1 != 0 {
+			firstFrame = int64(header.OFrame1)
+		}
+	default:
+		return nil, 0, ErrInvalidHeader
+	}
+
+	if firstFrame < headerSize {
+		return nil, 0, ErrInvalidHeader
+	}
+	return a, firstFrame, nil
+}
+
+// sniff checks the header more than the 2-byte magic at offset 4, which also appears in other formats
+// (e.g. a JPEG whose first segment is 0x11af bytes long).
+func sniff(b []byte, magic uint16) bool {
+	if len(b) < headerSize {
+		return false
+	}
+	size := binary.LittleEndian.Uint32(b[0:4])
+	frames := binary.LittleEndian.Uint16(b[6:8])
+	depth := binary.LittleEndian.Uint16(b[12:14])
+	return binary.LittleEndian.Uint16(b[4:6]) == magic && size >= headerSize && frames > 0 && isValidDepth(depth)
+}
+
+// isValidDepth reports whether depth is 8-bit palette, or 15, 16 or 24-bit color of DTA and later FLC files.
+func isValidDepth(depth uint16) bool {
+	switch depth {
+	case 8, 15, 16, 24:
+		return true
+	}
+	return false
+}
+
+func sniffFLI(b []byte) bool {
+	return sniff(b, magicFLI)
+}
+
+func sniffFLC(b []byte) bool {
+	if !sniff(b, magicFLC) {
+		return false
+	}
+	oFrame1 := binary.LittleEndian.Uint32(b[80:84])
+	return oFrame1 == 0 || oFrame1 >= headerSize
+}
+
+func (d *decoder) decodeChunkHeader() (chd chunkHeaderData, err error) {
+	if err = d.read(&chd.size); err != nil {
+		return
+	}
+	if err = d.read(&chd.chunkType); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+
+	if chd.size < chunkHeaderSize {
+		err = ErrInvalidChunk
+	}
+	return
+}
+
+// countFrames counts frame chunks, skipping prefix chunks. It stops counting when the count reaches limit.
+func (d *decoder) countFrames(limit int) (int, error) {
+	count := 0
+	for count < limit {
+		chd, err := d.decodeChunkHeader()
+		if err != nil {
+			if err == io.EOF {
+				return count, ErrFrameCountMismatch
+			}
+			return count, err
+		}
+
+		if chd.chunkType == chunkTypeFrame {
+			count++
+		} else if chd.chunkType != chunkTypePrefix {
+			return count, ErrInvalidChunk
+		}
+
+		if err := d.Advance(uint(chd.size - chunkHeaderSize)); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// decodeAnimation reads the header and verifies that the declared number of frames exists.
+func (d *decoder) decodeAnimation() (*Animation, error) {
+	a, firstFrame, err := d.decodeHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.SeekTo(firstFrame); err != nil {
+		return nil, err
+	}
+	if _, err := d.countFrames(a.Frames); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (d *decoder) inspect() (*midec.Info, error) {
+	a, err := d.decodeAnimation()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &midec.Info{Kind: midec.KindStatic, Frames: a.Frames, Width: a.Width, Height: a.Height}
+	if a.Frames >= 2 {
+		info.Kind = midec.KindAnimated
+		info.Duration = time.Duration(a.Frames) * a.Delay()
+	}
+	return info, nil
+}
+
+// DecodeAnimation reads the header and verifies the frame count by walking the frame chunks.
+func DecodeAnimation(r io.Reader) (*Animation, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.decodeAnimation()
+}
+
+func isAnimated(r io.Reader) (bool, error) {
+	info, err := inspect(r)
+	if err != nil {
+		return false, err
+	}
+	return info.Kind.IsMultiImage(), nil
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.inspect()
+}
+
+func init() {
+	// sniffed formats are tried after the ones with a magic at the beginning
+	midec.RegisterSniffedFormat("fli", sniffFLI, isAnimated, inspect)
+	midec.RegisterSniffedFormat("flc", sniffFLC, isAnimated, inspect)
+}
@@ -0,0 +1,126 @@
+package flic
+
+import (
+	"encoding/binary"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testdataFolder = "../testdata/flic/"
+
+func Test_isAnimated(t *testing.T) {
+	t.Parallel()
+
+	runIsAnimated := func(filename string) (bool, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return isAnimated(fp)
+	}
+
+	testcases := []struct {
+		filename           string
+		expectedIsAnimated bool
+		expectedHasError   bool
+	}{
+		{"animated.fli", true, false},
+		{"animated.flc", true, false},
+		{"static.flc", false, false},
+		{"invalid-framecount.fli", false, true},
+		{"invalid-chunk.flc", false, true},
+		{"invalid-header.fli", false, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actualIsAnimated, actualErr := runIsAnimated(tc.filename)
+			if tc.expectedIsAnimated != actualIsAnimated {
+				t.Errorf("IsAnimated = %t; want %t", actualIsAnimated, tc.expectedIsAnimated)
+			}
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+		})
+	}
+}
+
+func Test_DecodeAnimation(t *testing.T) {
+	t.Parallel()
+
+	runDecodeAnimation := func(filename string) (*Animation, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return DecodeAnimation(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expected         *Animation
+		expectedDelay    time.Duration
+		expectedHasError bool
+	}{
+		{"animated.fli", &Animation{Width: 320, Height: 200, Frames: 3, Speed: 5}, 5 * time.Second / 70, false},
+		{"animated.flc", &Animation{IsFLC: true, Width: 64, Height: 48, Frames: 2, Speed: 100}, 100 * time.Millisecond, false},
+		{"invalid-framecount.fli", nil, 0, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runDecodeAnimation(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Animation = %+v; want %+v", actual, tc.expected)
+			}
+			if actual.Delay() != tc.expectedDelay {
+				t.Errorf("Delay = %v; want %v", actual.Delay(), tc.expectedDelay)
+			}
+		})
+	}
+}
+
+func Test_sniff(t *testing.T) {
+	t.Parallel()
+
+	header, err := os.ReadFile(testdataFolder + "animated.flc")
+	if err != nil {
+		panic(err)
+	}
+	header = header[:headerSize]
+
+	testcases := []struct {
+		depth    uint16
+		expected bool
+	}{
+		{8, true},
+		{15, true},
+		{16, true},
+		{24, true},
+		{0, false},
+		{32, false},
+	}
+
+	for _, tc := range testcases {
+		b := append([]byte(nil), header...)
+		binary.LittleEndian.PutUint16(b[12:14], tc.depth)
+		if actual := sniffFLC(b); actual != tc.expected {
+			t.Errorf("sniffFLC(depth = %d) = %t; want %t", tc.depth, actual, tc.expected)
+		}
+	}
+}
@@ -39,6 +39,7 @@ import (
 	"bufio"
 	"errors"
 	"io"
+	"sort"
 	"sync"
 	"sync/atomic"
 )
@@ -48,34 +49,127 @@ var ErrFormat = errors.New("midec: unknown format")
 
 type format struct {
 	name, magic string
+	sniff       func([]byte) bool
 	isAnimated  func(io.Reader) (bool, error)
+	inspect     func(io.Reader) (*Info, error)
 }
 
+// sniffLen is the maximum number of bytes passed to the sniff function of RegisterSniffedFormat.
+const sniffLen = 1024
+
 var (
 	formatsMu     sync.Mutex
 	atomicFormats atomic.Value
 )
 
 // RegisterFormat registers an image format for use by IsAnimated.
+// Magic may contain "?" wildcards, so a magic at a non-zero offset is written with leading "?"s.
 func RegisterFormat(name, magic string, isAnimated func(io.Reader) (bool, error)) {
+	RegisterInspectableFormat(name, magic, isAnimated, nil)
+}
+
+// RegisterInspectableFormat registers an image format for use by IsAnimated and Inspect.
+// Either isAnimated or inspect may be nil. In that case, it is derived from the other one.
+func RegisterInspectableFormat(name, magic string, isAnimated func(io.Reader) (bool, error), inspect func(io.Reader) (*Info, error)) {
+	formatsMu.Lock()
+	formats, _ := atomicFormats.Load().([]format)
+	atomicFormats.Store(append(formats, format{name, magic, nil, isAnimated, inspect}))
+	formatsMu.Unlock()
+}
+
+// RegisterSniffedFormat registers an image format which does not have a fixed magic, such as SVG.
+// sniff is called with up to the first 1024 bytes and reports whether they are in the format.
+// Formats registered with a magic are tried before sniff is called.
+func RegisterSniffedFormat(name string, sniff func([]byte) bool, isAnimated func(io.Reader) (bool, error), inspect func(io.Reader) (*Info, error)) {
 	formatsMu.Lock()
 	formats, _ := atomicFormats.Load().([]format)
-	atomicFormats.Store(append(formats, format{name, magic, isAnimated}))
+	atomicFormats.Store(append(formats, format{name, "", sniff, isAnimated, inspect}))
 	formatsMu.Unlock()
 }
 
-// A reader is an io.Reader that can also peek ahead.
+// A reader is an io.Reader that can also peek ahead and skip.
 type reader interface {
 	io.Reader
 	Peek(int) ([]byte, error)
+	Discard(int) (int, error)
+}
+
+// seekReader is a bufio.Reader that can also seek the underlying io.ReadSeeker.
+type seekReader struct {
+	*bufio.Reader
+	rs io.ReadSeeker
+}
+
+// Seek implements io.Seeker. When the destination is still in the buffer, it is reused.
+func (s *seekReader) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekEnd {
+		pos, err := s.rs.Seek(offset, whence)
+		if err != nil {
+			return 0, err
+		}
+		s.Reset(s.rs)
+		return pos, nil
+	}
+
+	cur, err := s.rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	pos := cur - int64(s.Buffered())
+
+	target := offset
+	if whence == io.SeekCurrent {
+		target += pos
+	}
+
+	if pos <= target && target <= cur {
+		if _, err := s.Discard(int(target - pos)); err != nil {
+			return 0, err
+		}
+		return target, nil
+	}
+
+	if _, err := s.rs.Seek(target, io.SeekStart); err != nil {
+		return 0, err
+	}
+	s.Reset(s.rs)
+	return target, nil
+}
+
+// readerPool holds the readers returned by asReader, since bufio.Reader allocates a 4 KB buffer.
+var readerPool = sync.Pool{
+	New: func() interface{} {
+		return &seekReader{Reader: bufio.NewReader(nil)}
+	},
 }
 
 // asReader converts an io.Reader to a reader.
-func asReader(r io.Reader) reader {
+// If r is an io.ReadSeeker, the returned reader also implements io.Seeker.
+// The returned *seekReader is not nil when the reader was taken from readerPool, and should be
+// passed to releaseReader after the returned reader is no longer used.
+func asReader(r io.Reader) (reader, *seekReader) {
 	if rr, ok := r.(reader); ok {
-		return rr
+		return rr, nil
+	}
+
+	s := readerPool.Get().(*seekReader)
+	s.Reset(r)
+	if rs, ok := r.(io.ReadSeeker); ok {
+		s.rs = rs
+		return s, s
 	}
-	return bufio.NewReader(r)
+	return s.Reader, s
+}
+
+// releaseReader puts s back to readerPool. It does nothing when s is nil.
+func releaseReader(s *seekReader) {
+	if s == nil {
+		return
+	}
+	// drop the references to the reader
+	s.Reset(nil)
+	s.rs = nil
+	readerPool.Put(s)
 }
 
 // Match reports whether magic matches b. Magic may contain "?" wildcards.
@@ -95,21 +189,107 @@ func match(magic string, b []byte) bool {
 func sniff(r reader) format {
 	formats, _ := atomicFormats.Load().([]format)
 	for _, f := range formats {
+		if f.sniff != nil {
+			continue
+		}
 		b, err := r.Peek(len(f.magic))
 		if err == nil && match(f.magic, b) {
 			return f
 		}
 	}
+
+	var prefix []byte
+	for _, f := range formats {
+		if f.sniff == nil {
+			continue
+		}
+		if prefix == nil {
+			// a short file returns an error with the whole content
+			prefix, _ = r.Peek(sniffLen)
+		}
+		if f.sniff(prefix) {
+			return f
+		}
+	}
 	return format{}
 }
 
 // IsAnimated detects whether it is an animated image that has been encoded in a registered format.
 func IsAnimated(r io.Reader) (bool, error) {
-	rr := asReader(r)
+	rr, pooled := asReader(r)
+	defer releaseReader(pooled)
+
 	f := sniff(rr)
-	if f.isAnimated == nil {
-		return false, ErrFormat
+	if f.isAnimated != nil {
+		m, err := f.isAnimated(rr)
+		return m, err
+	}
+	if f.inspect != nil {
+		info, err := f.inspect(rr)
+		if err != nil {
+			return false, err
+		}
+		return info.Kind.IsMultiImage(), nil
+	}
+	return false, ErrFormat
+}
+
+// Inspect detects what kind of image it is, in more detail than IsAnimated.
+func Inspect(r io.Reader) (*Info, error) {
+	rr, pooled := asReader(r)
+	defer releaseReader(pooled)
+
+	f := sniff(rr)
+
+	var info *Info
+	switch {
+	case f.inspect != nil:
+		var err error
+		if info, err = f.inspect(rr); err != nil {
+			return nil, err
+		}
+	case f.isAnimated != nil:
+		m, err := f.isAnimated(rr)
+		if err != nil {
+			return nil, err
+		}
+		info = &Info{Kind: KindStatic}
+		if m {
+			info.Kind = KindAnimated
+		}
+	default:
+		return nil, ErrFormat
+	}
+
+	info.Format = f.name
+	return info, nil
+}
+
+// DetectFormat returns the name of the registered format of r's data without decoding it.
+// ErrFormat is returned when it is in none of them.
+func DetectFormat(r io.Reader) (string, error) {
+	rr, pooled := asReader(r)
+	defer releaseReader(pooled)
+
+	f := sniff(rr)
+	if f.name == "" {
+		return "", ErrFormat
+	}
+	return f.name, nil
+}
+
+// RegisteredFormatNames returns the names of the registered formats in sorted order without duplicates.
+func RegisteredFormatNames() []string {
+	formats, _ := atomicFormats.Load().([]format)
+	seen := make(map[string]struct{}, len(formats))
+	names := make([]string, 0, len(formats))
+	for _, f := range formats {
+		if _, ok := seen[f.name]; ok {
+			continue
+		}
+		seen[f.name] = struct{}{}
+		names = append(names, f.name)
 	}
-	m, err := f.isAnimated(rr)
-	return m, err
+	sort.Strings(names)
+	return names
 }
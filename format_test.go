@@ -2,14 +2,29 @@ package midec_test
 
 import (
 	"bufio"
+	"bytes"
 	"os"
 	"testing"
 
 	"github.com/sapphi-red/midec"
+	_ "github.com/sapphi-red/midec/ani"
+	_ "github.com/sapphi-red/midec/dicom"
+	_ "github.com/sapphi-red/midec/djvu"
+	_ "github.com/sapphi-red/midec/flic"
 	_ "github.com/sapphi-red/midec/gif"
+	_ "github.com/sapphi-red/midec/ico"
+	_ "github.com/sapphi-red/midec/isobmff"
+	_ "github.com/sapphi-red/midec/jpeg"
+	_ "github.com/sapphi-red/midec/jxl"
+	_ "github.com/sapphi-red/midec/lottie"
+	_ "github.com/sapphi-red/midec/matroska"
+	_ "github.com/sapphi-red/midec/mng"
 	_ "github.com/sapphi-red/midec/png"
+	_ "github.com/sapphi-red/midec/svg"
+	_ "github.com/sapphi-red/midec/tiff"
+	_ "github.com/sapphi-red/midec/ugoira"
 	_ "github.com/sapphi-red/midec/webp"
-	_ "github.com/sapphi-red/midec/isobmff"
+	_ "github.com/sapphi-red/midec/xcursor"
 )
 
 const testdataFolder = "testdata/"
@@ -34,6 +49,36 @@ func Test_IsAnimated(t *testing.T) {
 		{"png/animated.png", true, false},
 		{"webp/animated.webp", true, false},
 		{"isobmff/animated.avif", true, false},
+		{"jxl/animated.jxl", true, false},
+		{"jxl/animated-jxlc.jxl", true, false},
+		{"tiff/multipage.tif", true, false},
+		{"tiff/multipage-backward.tif", true, false},
+		{"tiff/multipage-bigtiff-be.tif", true, false},
+		{"ico/multi.ico", true, false},
+		{"ico/single.cur", false, false},
+		{"ani/animated.ani", true, false},
+		{"mng/animated.mng", true, false},
+		{"mng/static.jng", false, false},
+		{"jpeg/multi-disparity.mpo", true, false},
+		{"jpeg/static.jpg", false, false},
+		{"jpeg/motionphoto.jpg", true, false},
+		{"matroska/animated.webm", true, false},
+		{"flic/animated.fli", true, false},
+		{"flic/static.flc", false, false},
+		{"xcursor/animated", true, false},
+		{"xcursor/static", false, false},
+		{"svg/smil.svg", true, false},
+		{"svg/static.svg", false, false},
+		{"lottie/animated.json", true, false},
+		{"lottie/static.json", false, false},
+		{"lottie/animated.lottie", true, false},
+		{"djvu/multipage.djvu", true, false},
+		{"djvu/single.djvu", false, false},
+		{"dicom/cine.dcm", true, false},
+		{"dicom/static.dcm", false, false},
+		{"ugoira/animated.zip", true, false},
+		{"ugoira/static.zip", false, false},
+		{"matroska/video.webm", false, false},
 		{"invalid.txt", false, true},
 	}
 
@@ -69,3 +114,185 @@ func Test_IsAnimated_WithBuffer(t *testing.T) {
 		t.Errorf("Error = %v; want HasError = false", actualErr)
 	}
 }
+
+// a pipe is an *os.File, but fails to seek
+func Test_IsAnimated_Pipe(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		filename           string
+		expectedIsAnimated bool
+	}{
+		{"gif/animated.gif", true},
+		{"webp/animated.webp", true},
+		{"isobmff/motionphoto.heic", true},
+		{"isobmff/static-xmp.heic", false},
+	}
+
+	for _, tc := range testcases {
+		filename := tc.filename
+		data, err := os.ReadFile(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			panic(err)
+		}
+		go func() {
+			_, _ = pw.Write(data)
+			pw.Close()
+		}()
+
+		actualIsAnimated, actualErr := midec.IsAnimated(pr)
+		pr.Close()
+		if actualIsAnimated != tc.expectedIsAnimated {
+			t.Errorf("%s: IsAnimated = %t; want %t", filename, actualIsAnimated, tc.expectedIsAnimated)
+		}
+		if actualErr != nil {
+			t.Errorf("%s: Error = %v; want HasError = false", filename, actualErr)
+		}
+	}
+}
+
+// the bytes at offset 4 of the JPEG are the same as the FLI magic
+func Test_Inspect_FLIMagicInJPEG(t *testing.T) {
+	t.Parallel()
+
+	jpeg, err := os.ReadFile(testdataFolder + "jpeg/static.jpg")
+	if err != nil {
+		panic(err)
+	}
+	const segmentLength = 0x11af
+	var b bytes.Buffer
+	b.Write([]byte{0xff, 0xd8, 0xff, 0xe1, segmentLength >> 8, segmentLength & 0xff}) // SOI, APP1
+	b.Write(make([]byte, segmentLength-2))
+	b.Write(jpeg[2:])
+
+	actual, actualErr := midec.Inspect(&b)
+	if actualErr != nil {
+		t.Fatalf("Error = %v; want HasError = false", actualErr)
+	}
+	if actual.Format != "jpeg" {
+		t.Errorf("Format = %s; want jpeg", actual.Format)
+	}
+}
+
+func Test_Inspect_ICOMagicInAVIF(t *testing.T) {
+	t.Parallel()
+
+	avif, err := os.ReadFile(testdataFolder + "isobmff/animated.avif")
+	if err != nil {
+		panic(err)
+	}
+	const ftypSize, origFtypSize = 0x100, 0x28
+	var b bytes.Buffer
+	b.Write([]byte{0x00, 0x00, ftypSize >> 8, ftypSize & 0xff}) // same as the ico magic
+	b.Write(avif[4:origFtypSize])
+	for b.Len() < ftypSize {
+		b.WriteString("mif1")
+	}
+	b.Write(avif[origFtypSize:])
+
+	actual, actualErr := midec.Inspect(&b)
+	if actualErr != nil {
+		t.Fatalf("Error = %v; want HasError = false", actualErr)
+	}
+	if actual.Format != "isobmff" || actual.Kind != midec.KindAnimated {
+		t.Errorf("Format = %s, Kind = %v; want isobmff, %v", actual.Format, actual.Kind, midec.KindAnimated)
+	}
+}
+
+func Test_Inspect(t *testing.T) {
+	t.Parallel()
+
+	runInspect := func(filename string) (*midec.Info, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return midec.Inspect(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expectedFormat   string
+		expectedKind     midec.Kind
+		expectedHasError bool
+	}{
+		{"gif/animated.gif", "gif", midec.KindAnimated, false},
+		{"gif/static1.gif", "gif", midec.KindStatic, false},
+		{"tiff/multipage-backward.tif", "tiff", midec.KindMultiImage, false},
+		{"ico/multi.ico", "ico", midec.KindMultiImage, false},
+		{"jpeg/motionphoto.jpg", "jpeg", midec.KindEmbeddedVideo, false},
+		{"isobmff/motionphoto.heic", "isobmff", midec.KindEmbeddedVideo, false},
+		{"isobmff/animated.avif", "isobmff", midec.KindAnimated, false},
+		{"matroska/video.webm", "matroska", midec.KindVideo, false},
+		{"isobmff/movie.mp4", "isobmff", midec.KindVideo, false},
+		{"flic/animated.flc", "flc", midec.KindAnimated, false},
+		{"xcursor/animated", "xcursor", midec.KindAnimated, false},
+		{"svg/css-keyframes.svg", "svg", midec.KindAnimated, false},
+		{"lottie/animated.json", "lottie", midec.KindAnimated, false},
+		{"lottie/animated-v2.lottie", "dotlottie", midec.KindAnimated, false},
+		{"djvu/multipage.djvu", "djvu", midec.KindMultiImage, false},
+		{"dicom/cine-implicit.dcm", "dicom", midec.KindAnimated, false},
+		{"dicom/multiframe.dcm", "dicom", midec.KindMultiImage, false},
+		{"ugoira/animated-noanimation.zip", "ugoira", midec.KindAnimated, false},
+		{"ugoira/invalid-names.zip", "", midec.KindStatic, true},
+		{"matroska/animated.webm", "matroska", midec.KindAnimated, false},
+		{"invalid.txt", "", midec.KindStatic, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runInspect(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if actual.Format != tc.expectedFormat {
+				t.Errorf("Format = %s; want %s", actual.Format, tc.expectedFormat)
+			}
+			if actual.Kind != tc.expectedKind {
+				t.Errorf("Kind = %v; want %v", actual.Kind, tc.expectedKind)
+			}
+		})
+	}
+}
+
+func Test_DetectFormat(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		filename         string
+		expectedFormat   string
+		expectedHasError bool
+	}{
+		{"gif/animated.gif", "gif", false},
+		// the format is detected even if decoding fails
+		{"gif/invalid-block-unknown.gif", "gif", false},
+		{"svg/css-keyframes.svg", "svg", false},
+		{"invalid.txt", "", true},
+	}
+
+	for _, tc := range testcases {
+		fp, err := os.Open(testdataFolder + tc.filename)
+		if err != nil {
+			panic(err)
+		}
+		actual, actualErr := midec.DetectFormat(fp)
+		fp.Close()
+		if tc.expectedHasError != (actualErr != nil) {
+			t.Errorf("%s: Error = %v; want HasError = %t", tc.filename, actualErr, tc.expectedHasError)
+		}
+		if actual != tc.expectedFormat {
+			t.Errorf("%s: Format = %s; want %s", tc.filename, actual, tc.expectedFormat)
+		}
+	}
+}
@@ -2,8 +2,10 @@
 package gif
 
 import (
+	"encoding/binary"
 	"errors"
 	"io"
+	"time"
 
 	"github.com/sapphi-red/midec"
 )
@@ -26,6 +28,11 @@ type colorTableData struct {
 	size uint // Size of Global/Local Color Table
 }
 
+// loopExtensionIdentifiers are the Application Identifier and Authentication Code of the extensions which have a loop count.
+var loopExtensionIdentifiers = []string{"NETSCAPE2.0", "ANIMEXTS1.0"}
+
+const loopSubBlockID = 0x01
+
 type blockType uint
 
 const (
@@ -38,32 +45,72 @@ const (
 	blockTypeApplicationExtension
 )
 
+func (bt blockType) String() string {
+	switch bt {
+	case blockTypeTerminator:
+		return "Trailer"
+	case blockTypeImageBlock:
+		return "Image"
+	case blockTypeGraphicControlExtension:
+		return "Graphic Control Extension"
+	case blockTypeCommentExtension:
+		return "Comment Extension"
+	case blockTypePlainTextExtension:
+		return "Plain Text Extension"
+	case blockTypeApplicationExtension:
+		return "Application Extension"
+	}
+	return "Unknown"
+}
+
 type decoder struct {
 	midec.ReadAdvancer
 }
 
 func (d *decoder) readOneByte() (byte, error) {
-	buf := make([]byte, 1)
-	if _, err := d.ReadFull(buf); err != nil {
+	buf, err := d.Next(1)
+	if err != nil {
 		return 0, err
 	}
 	return buf[0], nil
 }
 
+func (d *decoder) readUint16() (uint16, error) {
+	buf, err := d.Next(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(buf), nil
+}
+
 func (d *decoder) skipHeader() error {
-	err := d.Advance(
+	_, _, err := d.decodeHeader()
+	return err
+}
+
+// decodeHeader reads the header and returns the logical screen size.
+func (d *decoder) decodeHeader() (width, height int, err error) {
+	err = d.Advance(
 		3 + // Signature
-			3 + // Version
-			2 + // Logical Screen Width
-			2, // Logical Screen Height
+			3, // Version
 	)
 	if err != nil {
-		return err
+		return
+	}
+
+	w, err := d.readUint16()
+	if err != nil {
+		return
+	}
+	h, err := d.readUint16()
+	if err != nil {
+		return
 	}
+	width, height = int(w), int(h)
 
 	gctd, err := d.decodeHeaderPackedFields()
 	if err != nil {
-		return err
+		return
 	}
 
 	gctdLen := uint(0)
@@ -76,7 +123,7 @@ func (d *decoder) skipHeader() error {
 			1 + // Pixel Aspect Ratio
 			gctdLen, // Global Color Table
 	)
-	return err
+	return
 }
 
 func (d *decoder) skipBlocksUntilTerminator() error {
@@ -192,6 +239,28 @@ func (d *decoder) skipGraphicControlExtensionBlock() error {
 	return err
 }
 
+// decodeGraphicControlExtensionBlock reads the block and returns the delay time.
+func (d *decoder) decodeGraphicControlExtensionBlock() (time.Duration, error) {
+	err := d.Advance(
+		1 + // Block Size
+			1, // Packed Fields
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	delay, err := d.readUint16() // 1/100 sec
+	if err != nil {
+		return 0, err
+	}
+
+	err = d.Advance(
+		1 + // Transparent Color Index
+			1, // Block Terminator
+	)
+	return time.Duration(delay) * 10 * time.Millisecond, err
+}
+
 func (d *decoder) skipCommentExtensionBlock() error {
 	if err := d.skipBlocksUntilTerminator(); err != nil {
 		return err
@@ -240,6 +309,43 @@ func (d *decoder) skipApplicationExtensionBlock() error {
 	return nil
 }
 
+// decodeApplicationExtensionBlock reads the block and returns the loop count if it is a looping extension.
+func (d *decoder) decodeApplicationExtensionBlock() (loops int, ok bool, err error) {
+	if err = d.Advance(1); err != nil { // Block Size
+		return
+	}
+
+	identifierBuf, err := d.Next(8 + 3) // Application Identifier, Application Authentication Code
+	if err != nil {
+		return
+	}
+	isLoopExtension := false
+	for _, id := range loopExtensionIdentifiers {
+		if string(identifierBuf) == id {
+			isLoopExtension = true
+		}
+	}
+
+	for {
+		var blockSize byte
+		if blockSize, err = d.readOneByte(); err != nil {
+			return
+		}
+		if blockSize == 0 {
+			return
+		}
+
+		var data []byte
+		if data, err = d.Next(int(blockSize)); err != nil {
+			return
+		}
+		if isLoopExtension && !ok && len(data) >= 3 && data[0] == loopSubBlockID {
+			loops = int(binary.LittleEndian.Uint16(data[1:3]))
+			ok = true
+		}
+	}
+}
+
 func (d *decoder) decodeImageBlockPackedFields() (ctd colorTableData, err error) {
 	packedFields, err := d.readOneByte()
 	if err != nil {
@@ -305,11 +411,89 @@ func (d *decoder) decode() (bool, error) {
 	return d.decodeBlocks()
 }
 
+// classify sets the kind from the number of frames after reading all blocks.
+func classify(info *midec.Info, loops int) *midec.Info {
+	if info.Frames >= 2 {
+		info.Kind = midec.KindAnimated
+		info.Loops = loops
+	} else {
+		info.Kind = midec.KindStatic
+		info.Duration = 0
+	}
+	return info
+}
+
+func (d *decoder) inspect() (*midec.Info, error) {
+	width, height, err := d.decodeHeader()
+	if err != nil {
+		return nil, err
+	}
+	// the blocks are visited after reading since the sizes are not known beforehand
+	d.Visit("Header", 0, d.Offset())
+
+	info := &midec.Info{Width: width, Height: height}
+	// without a looping extension, it is played once
+	loops := 1
+	for {
+		offset := d.Offset()
+		blockType, err := d.parseBlockType()
+		if err == io.EOF && info.Frames >= 2 {
+			// the trailer is missing, which isAnimated does not read after the second frame
+			return classify(info, loops), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch blockType {
+		case blockTypeTerminator:
+			d.Visit(blockType.String(), offset, d.Offset()-offset)
+			return classify(info, loops), nil
+
+		case blockTypeImageBlock:
+			err = d.skipImageBlock()
+			info.Frames++
+
+		case blockTypeGraphicControlExtension:
+			var delay time.Duration
+			delay, err = d.decodeGraphicControlExtensionBlock()
+			info.Duration += delay
+
+		case blockTypeCommentExtension:
+			err = d.skipCommentExtensionBlock()
+
+		case blockTypePlainTextExtension:
+			err = d.skipPlainTextExtensionBlock()
+
+		case blockTypeApplicationExtension:
+			var count int
+			var ok bool
+			count, ok, err = d.decodeApplicationExtensionBlock()
+			if ok {
+				// the loop count is the number of repetitions after the first play
+				loops = count + 1
+				if count == 0 {
+					loops = midec.LoopInfinite
+				}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		d.Visit(blockType.String(), offset, d.Offset()-offset)
+	}
+}
+
 func isAnimated(r io.Reader) (bool, error) {
 	d := decoder{*midec.NewReadAdvancer(r)}
 	return d.decode()
 }
 
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.inspect()
+}
+
 func init() {
-	midec.RegisterFormat("gif", gifHeader, isAnimated)
+	midec.RegisterInspectableFormat("gif", gifHeader, isAnimated, inspect)
 }
@@ -2,7 +2,11 @@ package gif
 
 import (
 	"os"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/sapphi-red/midec"
 )
 
 const testdataFolder = "../testdata/gif/"
@@ -25,6 +29,7 @@ func Test_isAnimated(t *testing.T) {
 	}{
 		{"loop.gif", true, false},
 		{"animated.gif", true, false},
+		{"animated-notrailer.gif", true, false},
 		{"static1.gif", false, false},
 		{"static2.gif", false, false},
 		{"static-plaintextextension.gif", false, false},
@@ -51,3 +56,48 @@ func Test_isAnimated(t *testing.T) {
 		})
 	}
 }
+
+func Test_inspect(t *testing.T) {
+	t.Parallel()
+
+	runInspect := func(filename string) (*midec.Info, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return inspect(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expected         *midec.Info
+		expectedHasError bool
+	}{
+		{"loop.gif", &midec.Info{Kind: midec.KindAnimated, Frames: 17, Loops: midec.LoopInfinite, Width: 242, Height: 175, Duration: 1990 * time.Millisecond}, false},
+		{"animated.gif", &midec.Info{Kind: midec.KindAnimated, Frames: 26, Loops: 1, Width: 242, Height: 175, Duration: 1980 * time.Millisecond}, false},
+		{"animated-notrailer.gif", &midec.Info{Kind: midec.KindAnimated, Frames: 26, Loops: 1, Width: 242, Height: 175, Duration: 1980 * time.Millisecond}, false},
+		{"static1.gif", &midec.Info{Kind: midec.KindStatic, Frames: 1, Width: 242, Height: 175}, false},
+		{"static-plaintextextension.gif", &midec.Info{Kind: midec.KindStatic, Frames: 1, Width: 242, Height: 175}, false},
+		{"invalid-header-length.gif", nil, true},
+		{"invalid-block-unknown.gif", nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runInspect(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Info = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
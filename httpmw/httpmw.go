@@ -0,0 +1,253 @@
+// Package httpmw provides net/http middleware which inspects uploaded images
+package httpmw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/sapphi-red/midec"
+)
+
+// ErrFormatNotAllowed indicates that the format is not in Policy.AllowedFormats.
+var ErrFormatNotAllowed = errors.New("midec: (httpmw) format not allowed")
+
+// ErrAnimated indicates that the image is animated while Policy.AllowAnimated is false.
+var ErrAnimated = errors.New("midec: (httpmw) animated image not allowed")
+
+// ErrTooManyFrames indicates that the image has more frames than Policy.MaxFrames.
+var ErrTooManyFrames = errors.New("midec: (httpmw) too many frames")
+
+// ErrTooLong indicates that the animation is longer than Policy.MaxDuration.
+var ErrTooLong = errors.New("midec: (httpmw) animation too long")
+
+// ErrBodyTooLarge indicates that the body had to be buffered but was larger than Options.MaxMemory.
+var ErrBodyTooLarge = errors.New("midec: (httpmw) body too large")
+
+// ErrInvalidMultipart indicates that the multipart body was broken.
+var ErrInvalidMultipart = errors.New("midec: (httpmw) invalid multipart body")
+
+const defaultMaxMemory = 32 << 20
+
+// Policy decides which images are accepted.
+type Policy struct {
+	// AllowedFormats is the names of the registered formats to be accepted. All formats are accepted when empty.
+	AllowedFormats []string
+	// AllowAnimated accepts the images which midec.IsAnimated reports true for.
+	AllowAnimated bool
+	// MaxFrames is the maximum number of frames. There is no limit when 0.
+	MaxFrames int
+	// MaxDuration is the maximum length of an animation. There is no limit when 0.
+	MaxDuration time.Duration
+}
+
+// Check returns the first rule that info violates, or nil.
+func (p *Policy) Check(info *midec.Info) error {
+	if len(p.AllowedFormats) > 0 {
+		allowed := false
+		for _, format := range p.AllowedFormats {
+			if info.Format == format {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return ErrFormatNotAllowed
+		}
+	}
+	if !p.AllowAnimated && info.Kind.IsMultiImage() {
+		return ErrAnimated
+	}
+	if p.MaxFrames > 0 && info.Frames > p.MaxFrames {
+		return ErrTooManyFrames
+	}
+	if p.MaxDuration > 0 && info.Duration > p.MaxDuration {
+		return ErrTooLong
+	}
+	return nil
+}
+
+// Options configures Middleware.
+type Options struct {
+	Policy Policy
+	// TagOnly makes the middleware attach the results to the request context without rejecting.
+	TagOnly bool
+	// MaxMemory is the maximum size of a body buffered in memory. It is 32 MB when 0.
+	// A multipart body is always buffered, and a raw body is buffered as far as the decoder reads
+	// (the whole body for the formats which need io.Seeker).
+	MaxMemory int64
+}
+
+// Result is the result of a raw body or a file part.
+type Result struct {
+	// FieldName and FileName are the form field name and the file name of a part. They are empty for a raw body.
+	FieldName string
+	FileName  string
+	// Info is nil when inspecting failed.
+	Info *midec.Info
+	// Err is the error while inspecting, or the rule of Policy that the image violates.
+	Err error
+}
+
+// StatusCode returns the status code to reject the request with, or 0 when accepted.
+func (res *Result) StatusCode() int {
+	switch {
+	case res.Err == nil:
+		return 0
+	case errors.Is(res.Err, midec.ErrFormat), errors.Is(res.Err, ErrFormatNotAllowed):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(res.Err, ErrBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(res.Err, ErrInvalidMultipart):
+		return http.StatusBadRequest
+	}
+	return http.StatusUnprocessableEntity
+}
+
+type contextKey struct{}
+
+// ResultsFromContext returns the results attached by Middleware.
+// They are in the order of the parts for a multipart body.
+func ResultsFromContext(ctx context.Context) []Result {
+	results, _ := ctx.Value(contextKey{}).([]Result)
+	return results
+}
+
+// readCloser restores the body with the bytes already consumed.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// limitedBuffer keeps the bytes read through io.TeeReader up to limit.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int64
+}
+
+// Write keeps p even when exceeding the limit, so that the body can still be restored.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n, _ := b.Buffer.Write(p)
+	if int64(b.Len()) > b.limit {
+		return n, ErrBodyTooLarge
+	}
+	return n, nil
+}
+
+// readLimited reads r up to limit. The bytes read are returned even on an error.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return b, err
+	}
+	if int64(len(b)) > limit {
+		return b, ErrBodyTooLarge
+	}
+	return b, nil
+}
+
+func (opts *Options) check(info *midec.Info, err error) Result {
+	if err != nil {
+		return Result{Err: err}
+	}
+	return Result{Info: info, Err: opts.Policy.Check(info)}
+}
+
+// inspectRaw inspects the body while keeping the bytes consumed, and restores the body.
+func (opts *Options) inspectRaw(r *http.Request, maxMemory int64) Result {
+	body := r.Body
+	consumed := &limitedBuffer{limit: maxMemory}
+	info, err := midec.Inspect(io.TeeReader(body, consumed))
+	if !errors.Is(err, midec.ErrNotSeekable) {
+		r.Body = readCloser{io.MultiReader(&consumed.Buffer, body), body}
+		return opts.check(info, err)
+	}
+
+	// the format needs random access, so the whole body is buffered
+	b, err := readLimited(io.MultiReader(&consumed.Buffer, body), maxMemory)
+	r.Body = readCloser{io.MultiReader(bytes.NewReader(b), body), body}
+	if err != nil {
+		return Result{Err: err}
+	}
+	return opts.check(midec.Inspect(bytes.NewReader(b)))
+}
+
+// inspectMultipart buffers the body, inspects the file parts and restores the body.
+func (opts *Options) inspectMultipart(r *http.Request, boundary string, maxMemory int64) []Result {
+	body := r.Body
+	b, err := readLimited(body, maxMemory)
+	r.Body = readCloser{io.MultiReader(bytes.NewReader(b), body), body}
+	if err != nil {
+		return []Result{{Err: err}}
+	}
+
+	var results []Result
+	mr := multipart.NewReader(bytes.NewReader(b), boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return results
+		}
+		if err != nil {
+			return append(results, Result{Err: ErrInvalidMultipart})
+		}
+		if part.FileName() == "" {
+			continue
+		}
+
+		data, err := ioutil.ReadAll(part)
+		if err != nil {
+			return append(results, Result{FieldName: part.FormName(), FileName: part.FileName(), Err: ErrInvalidMultipart})
+		}
+		res := opts.check(midec.Inspect(bytes.NewReader(data)))
+		res.FieldName, res.FileName = part.FormName(), part.FileName()
+		results = append(results, res)
+	}
+}
+
+func (opts *Options) inspect(r *http.Request) []Result {
+	maxMemory := opts.MaxMemory
+	if maxMemory <= 0 {
+		maxMemory = defaultMaxMemory
+	}
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil && mediaType == "multipart/form-data" && params["boundary"] != "" {
+		return opts.inspectMultipart(r, params["boundary"], maxMemory)
+	}
+	return []Result{opts.inspectRaw(r, maxMemory)}
+}
+
+// Middleware returns middleware which inspects the request body, or each file part of a multipart/form-data body.
+// The body is restored for the next handler, which receives the results by ResultsFromContext.
+// Unless Options.TagOnly, a request including an image which Options.Policy does not accept is rejected with
+// 415 (unknown or not allowed format), 422 (corrupt image or policy violation), 413 or 400.
+// Requests without a body are passed through.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			results := opts.inspect(r)
+			if !opts.TagOnly {
+				for _, res := range results {
+					if status := res.StatusCode(); status != 0 {
+						http.Error(w, res.Err.Error(), status)
+						return
+					}
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), contextKey{}, results)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package httpmw
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sapphi-red/midec"
+	_ "github.com/sapphi-red/midec/gif"
+	_ "github.com/sapphi-red/midec/png"
+	_ "github.com/sapphi-red/midec/ugoira"
+)
+
+const testdataFolder = "../testdata/"
+
+func readTestdata(filename string) []byte {
+	b, err := os.ReadFile(testdataFolder + filename)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+type part struct {
+	fieldName string
+	fileName  string
+	data      []byte
+}
+
+func newMultipartRequest(parts []part) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, p := range parts {
+		var w io.Writer
+		var err error
+		if p.fileName == "" {
+			w, err = mw.CreateFormField(p.fieldName)
+		} else {
+			w, err = mw.CreateFormFile(p.fieldName, p.fileName)
+		}
+		if err != nil {
+			panic(err)
+		}
+		if _, err := w.Write(p.data); err != nil {
+			panic(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		panic(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+// serve runs the middleware and returns the response, and the body and the results seen by the next handler.
+func serve(opts Options, r *http.Request) (*http.Response, []byte, []Result) {
+	var body []byte
+	var results []Result
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			panic(err)
+		}
+		results = ResultsFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	Middleware(opts)(next).ServeHTTP(rec, r)
+	return rec.Result(), body, results
+}
+
+func Test_Middleware_Raw(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name           string
+		filename       string
+		opts           Options
+		expectedStatus int
+	}{
+		{"static", "png/static.png", Options{}, http.StatusNoContent},
+		{"animated", "gif/animated.gif", Options{}, http.StatusUnprocessableEntity},
+		{"animated allowed", "gif/animated.gif", Options{Policy: Policy{AllowAnimated: true}}, http.StatusNoContent},
+		{"format allowed", "png/static.png", Options{Policy: Policy{AllowedFormats: []string{"png", "jpeg"}}}, http.StatusNoContent},
+		{"format not allowed", "gif/static1.gif", Options{Policy: Policy{AllowedFormats: []string{"png", "jpeg"}}}, http.StatusUnsupportedMediaType},
+		{"max frames", "gif/animated.gif", Options{Policy: Policy{AllowAnimated: true, MaxFrames: 25}}, http.StatusUnprocessableEntity},
+		{"max duration", "gif/animated.gif", Options{Policy: Policy{AllowAnimated: true, MaxDuration: time.Second}}, http.StatusUnprocessableEntity},
+		{"unknown format", "lottie/animated.json", Options{}, http.StatusUnsupportedMediaType},
+		{"corrupt", "gif/invalid-block-unknown.gif", Options{}, http.StatusUnprocessableEntity},
+		// inspecting GIF reads until the trailer
+		{"too large", "gif/animated.gif", Options{Policy: Policy{AllowAnimated: true}, MaxMemory: 10000}, http.StatusRequestEntityTooLarge},
+		// inspecting PNG stops at IDAT
+		{"too large but not read", "png/static.png", Options{MaxMemory: 1000}, http.StatusNoContent},
+		{"seekable format", "ugoira/animated.zip", Options{Policy: Policy{AllowAnimated: true}}, http.StatusNoContent},
+		{"seekable format too large", "ugoira/animated.zip", Options{Policy: Policy{AllowAnimated: true}, MaxMemory: 100}, http.StatusRequestEntityTooLarge},
+		{"tag only", "gif/animated.gif", Options{TagOnly: true}, http.StatusNoContent},
+		{"tag only too large", "gif/animated.gif", Options{TagOnly: true, MaxMemory: 10000}, http.StatusNoContent},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := readTestdata(tc.filename)
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+			resp, body, _ := serve(tc.opts, r)
+			if resp.StatusCode != tc.expectedStatus {
+				t.Errorf("StatusCode = %d; want %d", resp.StatusCode, tc.expectedStatus)
+			}
+			// the body should be intact for the next handler
+			if resp.StatusCode == http.StatusNoContent && !bytes.Equal(body, data) {
+				t.Errorf("Body length = %d; want %d", len(body), len(data))
+			}
+		})
+	}
+}
+
+func Test_Middleware_Multipart(t *testing.T) {
+	t.Parallel()
+
+	parts := []part{
+		{"title", "", []byte("hello")},
+		{"image", "static.png", readTestdata("png/static.png")},
+		{"image", "animated.gif", readTestdata("gif/animated.gif")},
+	}
+
+	testcases := []struct {
+		name           string
+		opts           Options
+		expectedStatus int
+	}{
+		{"animated part", Options{}, http.StatusUnprocessableEntity},
+		{"animated allowed", Options{Policy: Policy{AllowAnimated: true}}, http.StatusNoContent},
+		{"too large", Options{Policy: Policy{AllowAnimated: true}, MaxMemory: 1000}, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := newMultipartRequest(parts)
+			data, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				panic(err)
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+			resp, body, _ := serve(tc.opts, r)
+			if resp.StatusCode != tc.expectedStatus {
+				t.Errorf("StatusCode = %d; want %d", resp.StatusCode, tc.expectedStatus)
+			}
+			if resp.StatusCode == http.StatusNoContent && !bytes.Equal(body, data) {
+				t.Errorf("Body length = %d; want %d", len(body), len(data))
+			}
+		})
+	}
+}
+
+func Test_Middleware_Multipart_FormFile(t *testing.T) {
+	t.Parallel()
+
+	data := readTestdata("gif/animated.gif")
+	r := newMultipartRequest([]part{{"image", "animated.gif", data}})
+
+	var actual []byte
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, _, err := r.FormFile("image")
+		if err != nil {
+			t.Errorf("Error = %v; want HasError = false", err)
+			return
+		}
+		defer f.Close()
+		if actual, err = ioutil.ReadAll(f); err != nil {
+			panic(err)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	Middleware(Options{Policy: Policy{AllowAnimated: true}})(next).ServeHTTP(rec, r)
+	if !bytes.Equal(actual, data) {
+		t.Errorf("File length = %d; want %d", len(actual), len(data))
+	}
+}
+
+func Test_Middleware_TagOnly(t *testing.T) {
+	t.Parallel()
+
+	r := newMultipartRequest([]part{
+		{"a", "static.png", readTestdata("png/static.png")},
+		{"b", "notes.txt", []byte("not an image")},
+		{"c", "animated.gif", readTestdata("gif/animated.gif")},
+	})
+	_, _, actual := serve(Options{TagOnly: true}, r)
+
+	expected := []Result{
+		{
+			FieldName: "a",
+			FileName:  "static.png",
+			Info:      &midec.Info{Format: "png", Kind: midec.KindStatic, Frames: 1, Width: 242, Height: 175},
+		},
+		{FieldName: "b", FileName: "notes.txt", Err: midec.ErrFormat},
+		{
+			FieldName: "c",
+			FileName:  "animated.gif",
+			Info: &midec.Info{
+				Format: "gif", Kind: midec.KindAnimated, Frames: 26, Loops: 1, Width: 242, Height: 175,
+				Duration: 1980 * time.Millisecond,
+			},
+			Err: ErrAnimated,
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Results = %+v; want %+v", actual, expected)
+	}
+}
+
+func Test_Middleware_NoBody(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	resp, _, results := serve(Options{}, r)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("StatusCode = %d; want %d", resp.StatusCode, http.StatusNoContent)
+	}
+	if results != nil {
+		t.Errorf("Results = %+v; want nil", results)
+	}
+}
@@ -0,0 +1,295 @@
+// Package ico implements a multi-image ICO / CUR detector
+package ico
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/sapphi-red/midec"
+)
+
+const pngHeader = "\x89PNG\r\n\x1a\n"
+
+// ErrNoImage indicates that the directory did not have any entries.
+var ErrNoImage = errors.New("midec: (ico) no image in directory")
+
+// ErrInvalidHeader indicates that the reserved field or the resource type of the ICONDIR was invalid.
+var ErrInvalidHeader = errors.New("midec: (ico) invalid header")
+
+// ErrInvalidImage indicates that the image pointed by an entry was neither PNG nor BMP.
+var ErrInvalidImage = errors.New("midec: (ico) invalid image data")
+
+const (
+	resourceTypeIcon   = 1
+	resourceTypeCursor = 2
+)
+
+const (
+	iconDirSize      = 6
+	iconDirEntrySize = 16
+)
+
+// ImageFormat is the encoding of an image stored in the directory.
+type ImageFormat int
+
+const (
+	// FormatBMP is a BMP without BITMAPFILEHEADER.
+	FormatBMP ImageFormat = iota
+	// FormatPNG is an embedded PNG.
+	FormatPNG
+)
+
+func (f ImageFormat) String() string {
+	if f == FormatPNG {
+		return "png"
+	}
+	return "bmp"
+}
+
+// Entry describes an image in the directory.
+type Entry struct {
+	Width    int
+	Height   int
+	BitDepth int
+	Format   ImageFormat
+	// HotspotX and HotspotY are only set for cursors.
+	HotspotX int
+	HotspotY int
+
+	offset uint32
+}
+
+// Directory is the list of images in an ICO / CUR file.
+type Directory struct {
+	IsCursor bool
+	Entries  []Entry
+}
+
+type iconDirData struct {
+	isCursor bool
+	count    uint16
+}
+
+type decoder struct {
+	midec.ReadAdvancer
+}
+
+func (d *decoder) read(data interface{}) error {
+	return d.ReadBinary(binary.LittleEndian, data)
+}
+
+func (d *decoder) decodeIconDir() (idd iconDirData, err error) {
+	var header struct {
+		Reserved     uint16
+		ResourceType uint16
+	}
+	if err = d.read(&header); err != nil {
+		return
+	}
+	if header.Reserved != 0 || (header.ResourceType != resourceTypeIcon && header.ResourceType != resourceTypeCursor) {
+		err = ErrInvalidHeader
+		return
+	}
+	idd.isCursor = header.ResourceType == resourceTypeCursor
+
+	if err = d.read(&idd.count); err != nil {
+		return
+	}
+	if idd.count == 0 {
+		err = ErrNoImage
+	}
+	return
+}
+
+func (d *decoder) decodeIconDirEntry(isCursor bool) (e Entry, err error) {
+	var entry struct {
+		Width      uint8
+		Height     uint8
+		ColorCount uint8
+		Reserved   uint8
+		Planes     uint16 // Hotspot X for cursors
+		BitCount   uint16 // Hotspot Y for cursors
+		BytesInRes uint32
+		Offset     uint32
+	}
+	if err = d.read(&entry); err != nil {
+		return
+	}
+
+	e.Width = int(entry.Width)
+	if e.Width == 0 {
+		e.Width = 256
+	}
+	e.Height = int(entry.Height)
+	if e.Height == 0 {
+		e.Height = 256
+	}
+
+	if isCursor {
+		e.HotspotX = int(entry.Planes)
+		e.HotspotY = int(entry.BitCount)
+	} else {
+		e.BitDepth = int(entry.BitCount)
+	}
+	e.offset = entry.Offset
+	return
+}
+
+// decodeImageHeader reads the beginning of the image and completes e.
+func (d *decoder) decodeImageHeader(e *Entry) error {
+	if err := d.SeekTo(int64(e.offset)); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 26)
+	if _, err := d.ReadFull(buf[:16]); err != nil {
+		return err
+	}
+
+	if string(buf[:8]) == pngHeader {
+		if _, err := d.ReadFull(buf[16:]); err != nil {
+			return err
+		}
+		if string(buf[12:16]) != "IHDR" {
+			return ErrInvalidImage
+		}
+
+		e.Format = FormatPNG
+		e.Width = int(binary.BigEndian.Uint32(buf[16:20]))
+		e.Height = int(binary.BigEndian.Uint32(buf[20:24]))
+		e.BitDepth = int(buf[24]) * pngChannels(buf[25])
+		return nil
+	}
+
+	// BITMAPINFOHEADER or later
+	if binary.LittleEndian.Uint32(buf[0:4]) < 40 {
+		return ErrInvalidImage
+	}
+
+	e.Format = FormatBMP
+	if e.BitDepth == 0 {
+		e.BitDepth = int(binary.LittleEndian.Uint16(buf[14:16]))
+	}
+	return nil
+}
+
+func pngChannels(colorType byte) int {
+	switch colorType {
+	case 2: // Truecolor
+		return 3
+	case 4: // Greyscale with alpha
+		return 2
+	case 6: // Truecolor with alpha
+		return 4
+	}
+	return 1
+}
+
+// decodeEntries reads the ICONDIR and its entries.
+// The entries are appended as they are read, since the count is not trusted.
+func (d *decoder) decodeEntries() (iconDirData, []Entry, error) {
+	idd, err := d.decodeIconDir()
+	if err != nil {
+		return idd, nil, err
+	}
+
+	var entries []Entry
+	for i := 0; i < int(idd.count); i++ {
+		e, err := d.decodeIconDirEntry(idd.isCursor)
+		if err != nil {
+			return idd, nil, err
+		}
+		entries = append(entries, e)
+	}
+	return idd, entries, nil
+}
+
+func (d *decoder) decodeDirectory() (*Directory, error) {
+	idd, entries, err := d.decodeEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	// visit images in the order of offsets to avoid going backward
+	order := make([]*Entry, len(entries))
+	for i := range entries {
+		order[i] = &entries[i]
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return order[i].offset < order[j].offset
+	})
+	for _, e := range order {
+		if err := d.decodeImageHeader(e); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Directory{
+		IsCursor: idd.isCursor,
+		Entries:  entries,
+	}, nil
+}
+
+func (d *decoder) decode() (bool, error) {
+	_, entries, err := d.decodeEntries()
+	if err != nil {
+		return false, err
+	}
+	return len(entries) >= 2, nil
+}
+
+// DecodeDirectory reads the ICONDIR and the header of each image.
+func DecodeDirectory(r io.Reader) (*Directory, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.decodeDirectory()
+}
+
+func (d *decoder) inspect() (*midec.Info, error) {
+	_, entries, err := d.decodeEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) >= 2 {
+		return &midec.Info{Kind: midec.KindMultiImage, Frames: len(entries)}, nil
+	}
+	return &midec.Info{Kind: midec.KindStatic, Frames: 1}, nil
+}
+
+// sniff checks the header more than the 4-byte magic, which also appears in other formats
+// (e.g. an ISOBMFF file whose ftyp box is 256 bytes long).
+func sniff(b []byte, resourceType uint16) bool {
+	if len(b) < iconDirSize+iconDirEntrySize {
+		return false
+	}
+	reserved := binary.LittleEndian.Uint16(b[0:2])
+	count := binary.LittleEndian.Uint16(b[4:6])
+	entryReserved := b[iconDirSize+3]
+	return reserved == 0 && binary.LittleEndian.Uint16(b[2:4]) == resourceType && count > 0 && entryReserved == 0
+}
+
+func sniffICO(b []byte) bool {
+	return sniff(b, resourceTypeIcon)
+}
+
+func sniffCUR(b []byte) bool {
+	return sniff(b, resourceTypeCursor)
+}
+
+func isAnimated(r io.Reader) (bool, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.decode()
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.inspect()
+}
+
+func init() {
+	// sniffed formats are tried after the ones with a magic at the beginning
+	midec.RegisterSniffedFormat("ico", sniffICO, isAnimated, inspect)
+	midec.RegisterSniffedFormat("cur", sniffCUR, isAnimated, inspect)
+}
@@ -0,0 +1,117 @@
+package ico
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+const testdataFolder = "../testdata/ico/"
+
+func Test_isAnimated(t *testing.T) {
+	t.Parallel()
+
+	runIsAnimated := func(filename string) (bool, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return isAnimated(fp)
+	}
+
+	testcases := []struct {
+		filename           string
+		expectedIsAnimated bool
+		expectedHasError   bool
+	}{
+		{"multi.ico", true, false},
+		{"multi-reverse.ico", true, false},
+		{"multi.cur", true, false},
+		{"single.ico", false, false},
+		{"single.cur", false, false},
+		{"invalid-noimage.ico", false, true},
+		{"invalid-header.ico", false, true},
+		{"invalid-entry.ico", false, true},
+		{"invalid-type.ico", false, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actualIsAnimated, actualErr := runIsAnimated(tc.filename)
+			if tc.expectedIsAnimated != actualIsAnimated {
+				t.Errorf("IsAnimated = %t; want %t", actualIsAnimated, tc.expectedIsAnimated)
+			}
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+		})
+	}
+}
+
+func Test_DecodeDirectory(t *testing.T) {
+	t.Parallel()
+
+	runDecodeDirectory := func(filename string) (*Directory, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return DecodeDirectory(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expectedIsCursor bool
+		expectedEntries  []Entry
+		expectedHasError bool
+	}{
+		{"multi.ico", false, []Entry{
+			{Width: 16, Height: 16, BitDepth: 32, Format: FormatBMP},
+			{Width: 32, Height: 32, BitDepth: 8, Format: FormatBMP},
+			{Width: 256, Height: 256, BitDepth: 32, Format: FormatPNG},
+		}, false},
+		{"multi-reverse.ico", false, []Entry{
+			{Width: 16, Height: 16, BitDepth: 4, Format: FormatBMP},
+			{Width: 48, Height: 48, BitDepth: 24, Format: FormatPNG},
+		}, false},
+		{"multi.cur", true, []Entry{
+			{Width: 32, Height: 32, BitDepth: 1, Format: FormatBMP, HotspotX: 3, HotspotY: 5},
+			{Width: 64, Height: 64, BitDepth: 32, Format: FormatPNG, HotspotX: 6, HotspotY: 10},
+		}, false},
+		{"single.ico", false, []Entry{
+			{Width: 32, Height: 32, BitDepth: 32, Format: FormatBMP},
+		}, false},
+		{"invalid-noimage.ico", false, nil, true},
+		{"invalid-entry.ico", false, nil, true},
+		{"invalid-type.ico", false, nil, true},
+		{"invalid-image.ico", false, nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runDecodeDirectory(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if actual.IsCursor != tc.expectedIsCursor {
+				t.Errorf("IsCursor = %t; want %t", actual.IsCursor, tc.expectedIsCursor)
+			}
+			for i := range actual.Entries {
+				actual.Entries[i].offset = 0
+			}
+			if !reflect.DeepEqual(actual.Entries, tc.expectedEntries) {
+				t.Errorf("Entries = %+v; want %+v", actual.Entries, tc.expectedEntries)
+			}
+		})
+	}
+}
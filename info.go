@@ -0,0 +1,80 @@
+package midec
+
+import "time"
+
+// Kind classifies the images found in a file.
+type Kind int
+
+const (
+	// KindStatic is a single still image.
+	KindStatic Kind = iota
+	// KindAnimated is an image which has two or more frames played in sequence.
+	KindAnimated
+	// KindMultiImage is a file which has two or more independent images, such as pages or icon sizes.
+	KindMultiImage
+	// KindEmbeddedVideo is a still image which carries a video, such as a Motion Photo.
+	KindEmbeddedVideo
+	// KindVideo is a video container, such as Matroska / WebM.
+	KindVideo
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindAnimated:
+		return "animated"
+	case KindMultiImage:
+		return "multi-image"
+	case KindEmbeddedVideo:
+		return "embedded-video"
+	case KindVideo:
+		return "video"
+	}
+	return "static"
+}
+
+// IsMultiImage reports whether IsAnimated returns true for this kind.
+func (k Kind) IsMultiImage() bool {
+	return k == KindAnimated || k == KindMultiImage || k == KindEmbeddedVideo
+}
+
+// LoopInfinite is the value of Info.Loops for an animation played repeatedly forever.
+const LoopInfinite = -1
+
+// EmbeddedVideo locates a video appended to a still image.
+type EmbeddedVideo struct {
+	// OffsetFromEnd is the distance from the beginning of the video to the end of the file.
+	OffsetFromEnd int64
+	// Length is the size of the video.
+	Length int64
+}
+
+// Offset returns the offset of the video from the beginning of the file whose size is size.
+func (v EmbeddedVideo) Offset(size int64) int64 {
+	return size - v.OffsetFromEnd
+}
+
+// Info is the result of Inspect.
+type Info struct {
+	// Format is the name of the registered format.
+	Format string
+	Kind   Kind
+	// Video is set when Kind is KindEmbeddedVideo.
+	Video *EmbeddedVideo
+
+	// Frames is the number of frames, pages or images. It is 0 when unknown.
+	Frames int
+	// Loops is the number of times an animation is played. It is LoopInfinite when played forever, 0 when unknown.
+	Loops int
+	// Width and Height are the size of the canvas. They are 0 when unknown.
+	Width  int
+	Height int
+	// Duration is the length of an animation or a video. It is 0 when unknown.
+	Duration time.Duration
+	// TicksPerSecond is the time unit of the frame durations of an animation timed in ticks, such as JPEG XL.
+	// It is 0 when unknown.
+	TicksPerSecond float64
+	// Tracks is the number of tracks in a video container.
+	Tracks int
+	// HasAudio reports whether a video container has an audio track.
+	HasAudio bool
+}
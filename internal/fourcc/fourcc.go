@@ -0,0 +1,36 @@
+// Package fourcc converts the chunk types and the box types read by the detectors to strings
+package fourcc
+
+// common is the types seen in most files. Converting them does not allocate.
+var common = func() map[string]string {
+	types := []string{
+		// PNG, MNG and JNG
+		"IHDR", "PLTE", "IDAT", "IEND", "acTL", "fcTL", "fdAT", "tRNS", "gAMA", "cHRM", "sRGB", "iCCP",
+		"tEXt", "zTXt", "iTXt", "bKGD", "pHYs", "sBIT", "tIME", "eXIf",
+		"MHDR", "MEND", "TERM", "FRAM", "DEFI", "LOOP", "ENDL", "JHDR", "JDAT", "JSEP",
+		// RIFF based formats
+		"RIFF", "FORM", "LIST", "INFO", "WEBP", "VP8 ", "VP8L", "VP8X", "ANIM", "ANMF", "ALPH", "ICCP", "EXIF", "XMP ",
+		"ACON", "anih", "rate", "seq ", "fram", "icon", "AT&T", "DJVU", "DJVM", "DIRM", "NAVM", "Sjbz", "BG44", "FG44",
+		// ISOBMFF
+		"ftyp", "free", "skip", "mdat", "moov", "mvhd", "trak", "tkhd", "edts", "mdia", "mdhd", "hdlr", "minf", "stbl",
+		"udta", "meta", "pitm", "iloc", "iinf", "infe", "iref", "iprp", "ipco", "ipma", "idat", "uuid",
+		"vide", "soun", "pict", "mime",
+		"avif", "avis", "heic", "heix", "hevc", "mif1", "msf1", "isom", "iso2", "mp41", "mp42", "qt  ",
+		// JPEG XL container
+		"JXL ", "jxlc", "jxlp", "jbrd", "Exif",
+	}
+	m := make(map[string]string, len(types))
+	for _, t := range types {
+		m[t] = t
+	}
+	return m
+}()
+
+// String returns b as a string. It does not allocate when b is a common type.
+func String(b []byte) string {
+	// the conversion in the index expression does not allocate
+	if s, ok := common[string(b)]; ok {
+		return s
+	}
+	return string(b)
+}
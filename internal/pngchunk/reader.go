@@ -0,0 +1,73 @@
+// Package pngchunk implements a reader of PNG style chunks shared by the detectors
+package pngchunk
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/sapphi-red/midec"
+	"github.com/sapphi-red/midec/internal/fourcc"
+)
+
+const (
+	signatureSize   = 8
+	chunkHeaderSize = 4 + 4 // Length, Chunk Type
+	crcSize         = 4
+)
+
+// ChunkHeaderData is the header of a chunk.
+type ChunkHeaderData struct {
+	Length uint32
+	TypeID string
+}
+
+// Decoder reads chunks of PNG, MNG and JNG.
+type Decoder struct {
+	midec.ReadAdvancer
+}
+
+// NewDecoder creates Decoder.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{*midec.NewReadAdvancer(r)}
+}
+
+// ReadUint32 reads a big-endian uint32.
+func (d *Decoder) ReadUint32() (u uint32, err error) {
+	err = d.ReadBinary(binary.BigEndian, &u)
+	return
+}
+
+// SkipSignature skips the 8 bytes signature.
+func (d *Decoder) SkipSignature() error {
+	d.Visit("signature", d.Offset(), signatureSize)
+	return d.Advance(signatureSize)
+}
+
+// DecodeChunkHeader reads a chunk header.
+func (d *Decoder) DecodeChunkHeader() (chd ChunkHeaderData, err error) {
+	offset := d.Offset()
+	length, err := d.ReadUint32()
+	if err != nil {
+		return
+	}
+
+	typeIDBuf, err := d.Next(4)
+	if err != nil {
+		return
+	}
+
+	chd = ChunkHeaderData{
+		Length: length,
+		TypeID: fourcc.String(typeIDBuf),
+	}
+	d.Visit(chd.TypeID, offset, chunkHeaderSize+int64(length)+crcSize)
+	return chd, nil
+}
+
+// SkipChunk skips the data and the CRC of the chunk.
+func (d *Decoder) SkipChunk(chd ChunkHeaderData) error {
+	return d.Advance(
+		uint(chd.Length) + // data
+			4, // CRC
+	)
+}
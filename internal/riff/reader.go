@@ -0,0 +1,107 @@
+// Package riff implements a reader of RIFF (and big-endian IFF) chunks shared by the detectors
+package riff
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/sapphi-red/midec"
+	"github.com/sapphi-red/midec/internal/fourcc"
+)
+
+const chunkHeaderSize = 4 + 4 // FourCC, Size
+
+// ChunkHeaderData is the header of a chunk.
+type ChunkHeaderData struct {
+	FourCC   string
+	DataSize uint32
+}
+
+// Decoder reads chunks. The byte order is little-endian for RIFF and big-endian for IFF.
+type Decoder struct {
+	midec.ReadAdvancer
+	ByteOrder binary.ByteOrder
+}
+
+// NewDecoder creates Decoder.
+func NewDecoder(r io.Reader, byteOrder binary.ByteOrder) *Decoder {
+	return &Decoder{
+		ReadAdvancer: *midec.NewReadAdvancer(r),
+		ByteOrder:    byteOrder,
+	}
+}
+
+// ReadUint32 reads a uint32 in the byte order of the Decoder.
+func (d *Decoder) ReadUint32() (u uint32, err error) {
+	err = d.ReadBinary(d.ByteOrder, &u)
+	return
+}
+
+// ReadFourCC reads a four-character code.
+func (d *Decoder) ReadFourCC() (string, error) {
+	buf, err := d.Next(4)
+	if err != nil {
+		return "", err
+	}
+	return fourcc.String(buf), nil
+}
+
+// DecodeFormHeader reads 'RIFF' (or 'FORM'), the size and the form type, and returns the form type.
+func (d *Decoder) DecodeFormHeader() (string, error) {
+	offset := d.Offset()
+	chd, err := d.decodeChunkHeader()
+	if err != nil {
+		return "", err
+	}
+
+	formType, err := d.ReadFourCC()
+	if err != nil {
+		return "", err
+	}
+	if d.Tracing() {
+		d.Visit(chd.FourCC+" "+formType, offset, chunkSize(chd))
+	}
+	return formType, nil
+}
+
+// chunkSize returns the size of the chunk including the header and the padding byte.
+func chunkSize(chd ChunkHeaderData) int64 {
+	return chunkHeaderSize + int64(chd.DataSize) + int64(chd.DataSize&1)
+}
+
+// DecodeChunkHeader reads a chunk header.
+// io.EOF is returned only when there are no more chunks.
+func (d *Decoder) DecodeChunkHeader() (ChunkHeaderData, error) {
+	offset := d.Offset()
+	chd, err := d.decodeChunkHeader()
+	if err != nil {
+		return chd, err
+	}
+	d.Visit(chd.FourCC, offset, chunkSize(chd))
+	return chd, nil
+}
+
+func (d *Decoder) decodeChunkHeader() (chd ChunkHeaderData, err error) {
+	fourCC, err := d.ReadFourCC()
+	if err != nil {
+		return
+	}
+
+	dataSize, err := d.ReadUint32()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+
+	return ChunkHeaderData{
+		FourCC:   fourCC,
+		DataSize: dataSize,
+	}, nil
+}
+
+// SkipChunk skips the data of the chunk and its padding byte.
+func (d *Decoder) SkipChunk(chd ChunkHeaderData) error {
+	return d.Advance(uint(chd.DataSize) + uint(chd.DataSize&1))
+}
@@ -0,0 +1,140 @@
+// Package xmp implements a reader of XMP properties shared by the detectors
+package xmp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+
+	"github.com/sapphi-red/midec"
+)
+
+const (
+	nsGCamera   = "http://ns.google.com/photos/1.0/camera/"
+	nsContainer = "http://ns.google.com/photos/1.0/container/"
+	nsItem      = "http://ns.google.com/photos/1.0/container/item/"
+)
+
+const semanticMotionPhoto = "MotionPhoto"
+
+type containerItem struct {
+	mime     string
+	semantic string
+	length   int64
+	padding  int64
+}
+
+type motionPhotoData struct {
+	motionPhoto      string
+	microVideo       string
+	microVideoOffset int64
+	items            []containerItem
+}
+
+// isName reports whether n is prefix:local. Undeclared prefixes are also accepted.
+func isName(n xml.Name, ns, prefix, local string) bool {
+	return n.Local == local && (n.Space == ns || n.Space == prefix)
+}
+
+func parseInt(s string) int64 {
+	v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v
+}
+
+func (mpd *motionPhotoData) setCameraProperty(n xml.Name, value string) {
+	switch {
+	case isName(n, nsGCamera, "GCamera", "MotionPhoto"):
+		mpd.motionPhoto = strings.TrimSpace(value)
+	case isName(n, nsGCamera, "GCamera", "MicroVideo"):
+		mpd.microVideo = strings.TrimSpace(value)
+	case isName(n, nsGCamera, "GCamera", "MicroVideoOffset"):
+		mpd.microVideoOffset = parseInt(value)
+	}
+}
+
+func (mpd *motionPhotoData) decodeStartElement(se xml.StartElement) {
+	for _, attr := range se.Attr {
+		mpd.setCameraProperty(attr.Name, attr.Value)
+	}
+
+	if !isName(se.Name, nsContainer, "Container", "Item") {
+		return
+	}
+
+	var item containerItem
+	for _, attr := range se.Attr {
+		switch {
+		case isName(attr.Name, nsItem, "Item", "Mime"):
+			item.mime = attr.Value
+		case isName(attr.Name, nsItem, "Item", "Semantic"):
+			item.semantic = attr.Value
+		case isName(attr.Name, nsItem, "Item", "Length"):
+			item.length = parseInt(attr.Value)
+		case isName(attr.Name, nsItem, "Item", "Padding"):
+			item.padding = parseInt(attr.Value)
+		}
+	}
+	mpd.items = append(mpd.items, item)
+}
+
+// video locates the video from the collected properties.
+func (mpd *motionPhotoData) video() *midec.EmbeddedVideo {
+	if mpd.motionPhoto != "0" {
+		// items after the primary image are stored in order at the end of the file
+		var offsetFromEnd int64
+		for i := len(mpd.items) - 1; i >= 1; i-- {
+			item := mpd.items[i]
+			offsetFromEnd += item.length + item.padding
+			if item.semantic == semanticMotionPhoto && item.length > 0 {
+				return &midec.EmbeddedVideo{
+					OffsetFromEnd: offsetFromEnd,
+					Length:        item.length,
+				}
+			}
+		}
+	}
+
+	if mpd.microVideo == "1" && mpd.microVideoOffset > 0 {
+		return &midec.EmbeddedVideo{
+			OffsetFromEnd: mpd.microVideoOffset,
+			Length:        mpd.microVideoOffset,
+		}
+	}
+	return nil
+}
+
+// ParseMotionPhoto reads the Motion Photo (or the older Micro Video) properties from a XMP packet.
+// It returns nil when the packet does not declare an embedded video.
+// Since a broken XMP packet does not make the image itself broken, syntax errors are not reported
+// and the properties read until then are used.
+func ParseMotionPhoto(packet []byte) *midec.EmbeddedVideo {
+	var mpd motionPhotoData
+
+	dec := xml.NewDecoder(bytes.NewReader(packet))
+	dec.Strict = false
+
+	var pending xml.Name
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			mpd.decodeStartElement(t)
+			pending = t.Name
+		case xml.CharData:
+			// properties written as elements instead of attributes
+			mpd.setCameraProperty(pending, string(t))
+		case xml.EndElement:
+			pending = xml.Name{}
+		}
+	}
+
+	return mpd.video()
+}
@@ -1,11 +1,14 @@
-// Package isobmff implements a Animated HEIF / Animated AVIF detector
+// Package isobmff implements a Animated HEIF / Animated AVIF, HEIF Motion Photo and MP4 / QuickTime video detector
 package isobmff
 
 import (
 	"encoding/binary"
 	"io"
+	"time"
 
 	"github.com/sapphi-red/midec"
+	"github.com/sapphi-red/midec/internal/fourcc"
+	"github.com/sapphi-red/midec/internal/xmp"
 )
 
 const isobmmfHeader = "????ftyp"
@@ -33,36 +36,92 @@ type boxHeaderData struct {
 	boxType  string
 }
 
+// maxMetaDataSize is the maximum size of iinf entries, iloc box and XMP packet to be read into memory.
+const maxMetaDataSize = 1 << 20
+
+// maxEntrySize is the size of the largest infe box read without allocating.
+const maxEntrySize = 768
+
+const xmpContentType = "application/rdf+xml"
+
 type movieHeaderBoxData struct {
-	duration int64
+	timescale uint32
+	duration  int64
+}
+
+type movieBoxData struct {
+	header       movieHeaderBoxData
+	handlerTypes []string
+}
+
+func (mbd movieBoxData) hasTrack(handlerType string) bool {
+	for _, t := range mbd.handlerTypes {
+		if t == handlerType {
+			return true
+		}
+	}
+	return false
+}
+
+// isAnimated reports whether the movie is an image sequence, which has a valid duration and a pict track.
+func (mbd movieBoxData) isAnimated() bool {
+	return mbd.header.duration > 0 && mbd.hasTrack("pict")
+}
+
+func (mbd movieBoxData) duration() time.Duration {
+	if mbd.header.timescale == 0 || mbd.header.duration <= 0 {
+		return 0
+	}
+	return time.Duration(mbd.header.duration) * time.Second / time.Duration(mbd.header.timescale)
 }
 
 type handlerReferenceBoxData struct {
 	handlerType string
 }
 
+type itemLocationData struct {
+	offset int64
+	length int64
+}
+
 type decoder struct {
 	midec.ReadAdvancer
+	// locationBuf holds a small iloc box, since the items are looked up after reading iinf
+	locationBuf [maxEntrySize]byte
 }
 
 func (d *decoder) read(data interface{}) error {
-	return binary.Read(d.Reader, binary.BigEndian, data)
+	return d.ReadBinary(binary.BigEndian, data)
+}
+
+func (d *decoder) decodeBoxHeader() (boxHeaderData, error) {
+	offset := d.Offset()
+	bhd, err := d.decodeBoxHeaderFields()
+	if err != nil {
+		return bhd, err
+	}
+
+	size := int64(-1)
+	if !bhd.untilEnd {
+		size = d.Offset() - offset + bhd.dataSize
+	}
+	d.Visit(bhd.boxType, offset, size)
+	return bhd, nil
 }
 
-func (d *decoder) decodeBoxHeader() (bhd boxHeaderData, err error) {
+func (d *decoder) decodeBoxHeaderFields() (bhd boxHeaderData, err error) {
 	var size int32
 	err = d.read(&size)
 	if err != nil {
 		return
 	}
 
-	boxTypeBuf := make([]byte, 4)
-	_, err = d.ReadFull(boxTypeBuf)
+	boxTypeBuf, err := d.Next(4)
 	if err != nil {
 		return
 	}
 
-	boxType := string(boxTypeBuf)
+	boxType := fourcc.String(boxTypeBuf)
 
 	if size == 0 {
 		return boxHeaderData{
@@ -136,19 +195,19 @@ func (d *decoder) decodeFileTypeBox() (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	d.Visit("ftyp", 0, int64(size))
 
-	brandBuff := make([]byte, 4)
-	if _, err = d.ReadFull(brandBuff); err != nil {
+	brandBuff, err := d.Next(4)
+	if err != nil {
 		return false, err
 	}
+	brand := fourcc.String(brandBuff)
 
 	err = d.Advance(uint(size) - 4 - 4 - 4)
 	if err != nil {
 		return false, err
 	}
 
-	brand := string(brandBuff)
-
 	for _, b := range animatedableBrands {
 		if brand == b {
 			return true, nil
@@ -166,52 +225,59 @@ func (d *decoder) decodeMovieHeaderBox(dataSize int64) (mhbd movieHeaderBoxData,
 
 	if version == 1 {
 		err = d.Advance(
-			8 + // creation_time
-				8 + // modification_time
-				4, // timescale
+			3 + // (FullBox) flags
+				8 + // creation_time
+				8, // modification_time
 		)
 		if err != nil {
 			return
 		}
 
+		var timescale uint32
 		var duration int64
-		err = d.read(&duration)
-		if err != nil {
+		if err = d.read(&timescale); err != nil {
+			return
+		}
+		if err = d.read(&duration); err != nil {
 			return
 		}
 
-		err = d.Advance(uint(dataSize) - 1 - 8 - 8 - 4 - 8)
+		err = d.Advance(uint(dataSize) - 1 - 3 - 8 - 8 - 4 - 8)
 		if err != nil {
 			return
 		}
 
 		return movieHeaderBoxData{
-			duration: duration,
+			timescale: timescale,
+			duration:  duration,
 		}, nil
 	}
 
 	err = d.Advance(
-		4 + // creation_time
-			4 + // modification_time
-			4, // timescale
+		3 + // (FullBox) flags
+			4 + // creation_time
+			4, // modification_time
 	)
 	if err != nil {
 		return
 	}
 
-	var duration int32
-	err = d.read(&duration)
-	if err != nil {
+	var timescale, duration uint32
+	if err = d.read(&timescale); err != nil {
+		return
+	}
+	if err = d.read(&duration); err != nil {
 		return
 	}
 
-	err = d.Advance(uint(dataSize) - 1 - 4 - 4 - 4 - 4)
+	err = d.Advance(uint(dataSize) - 1 - 3 - 4 - 4 - 4 - 4)
 	if err != nil {
 		return
 	}
 
 	return movieHeaderBoxData{
-		duration: int64(duration),
+		timescale: timescale,
+		duration:  int64(duration),
 	}, nil
 }
 
@@ -225,119 +291,435 @@ func (d *decoder) decodeHandlerReferenceBox(dataSize int64) (hrbd handlerReferen
 		return
 	}
 
-	handlerTypeBuff := make([]byte, 4)
-	_, err = d.ReadFull(handlerTypeBuff)
+	handlerTypeBuff, err := d.Next(4)
 	if err != nil {
 		return
 	}
+	handlerType := fourcc.String(handlerTypeBuff)
 
-	err = d.Advance(uint(dataSize) - 1 - 3 - 1 - 4)
+	err = d.Advance(uint(dataSize) - 1 - 3 - 4 - 4)
 	if err != nil {
 		return
 	}
 
 	return handlerReferenceBoxData{
-		handlerType: string(handlerTypeBuff),
+		handlerType: handlerType,
 	}, nil
 }
 
-func (d *decoder) confirmTrackBoxIsPictureType(dataSize int64) (bool, error) {
+// decodeTrackBoxHandlerType returns the handler type of the track. It is empty when not found.
+func (d *decoder) decodeTrackBoxHandlerType(dataSize int64) (string, error) {
 	found, mdiaD, err := d.findBox("mdia", &dataSize)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 	if !found {
-		return false, nil
+		return "", nil
 	}
 
 	found, hdlrD, err := d.findBox("hdlr", &mdiaD.dataSize)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 	if !found {
-		return false, nil
+		return "", nil
 	}
 
 	hrbd, err := d.decodeHandlerReferenceBox(hdlrD.dataSize)
 	if err != nil {
-		return false, err
+		return "", err
 	}
+	return hrbd.handlerType, nil
+}
 
-	isPictureType := hrbd.handlerType == "pict"
-	return isPictureType, nil
+// decodeMovieBox reads moov box and returns the movie header and the handler types of the tracks.
+func (d *decoder) decodeMovieBox(moovSize int64) (mbd movieBoxData, err error) {
+	for moovSize > 0 {
+		bhd, err := d.decodeBoxHeader()
+		if err != nil {
+			return mbd, err
+		}
+		moovSize -= 4 + 4 + bhd.dataSize
+
+		switch bhd.boxType {
+		case "mvhd":
+			if mbd.header, err = d.decodeMovieHeaderBox(bhd.dataSize); err != nil {
+				return mbd, err
+			}
+		case "trak":
+			trakEnd := d.Offset() + bhd.dataSize
+			handlerType, err := d.decodeTrackBoxHandlerType(bhd.dataSize)
+			if err != nil {
+				return mbd, err
+			}
+			mbd.handlerTypes = append(mbd.handlerTypes, handlerType)
+
+			if err := d.SeekTo(trakEnd); err != nil {
+				return mbd, err
+			}
+		default:
+			if err := d.Advance(uint(bhd.dataSize)); err != nil {
+				return mbd, err
+			}
+		}
+	}
+	return mbd, nil
 }
 
-func (d *decoder) decode() (bool, error) {
-	isAnimatable, err := d.decodeFileTypeBox()
-	if err != nil {
-		return false, err
+// readBoxData reads the rest of the box into memory.
+// nil is returned when the box is larger than maxMetaDataSize, after skipping it.
+func (d *decoder) readBoxData(dataSize int64) ([]byte, error) {
+	if dataSize < 0 {
+		return nil, io.ErrUnexpectedEOF
 	}
-	if !isAnimatable {
-		return false, nil
+	if dataSize > maxMetaDataSize {
+		return nil, d.Advance(uint(dataSize))
 	}
 
-	found, moovhd, err := d.findBox("moov", nil)
-	if err != nil {
-		if err == io.EOF {
-			return false, nil
+	buf := make([]byte, dataSize)
+	if _, err := d.ReadFull(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// byteCursor reads big-endian integers from a byte slice.
+type byteCursor struct {
+	buf []byte
+	ok  bool
+}
+
+func (c *byteCursor) readUint(n int) uint64 {
+	if !c.ok || len(c.buf) < n {
+		c.ok = false
+		return 0
+	}
+
+	var v uint64
+	for _, b := range c.buf[:n] {
+		v = v<<8 | uint64(b)
+	}
+	c.buf = c.buf[n:]
+	return v
+}
+
+func (c *byteCursor) readFourCC() string {
+	if !c.ok || len(c.buf) < 4 {
+		c.ok = false
+		return ""
+	}
+
+	s := string(c.buf[:4])
+	c.buf = c.buf[4:]
+	return s
+}
+
+func (c *byteCursor) readString() string {
+	for i, b := range c.buf {
+		if b == 0 {
+			s := string(c.buf[:i])
+			c.buf = c.buf[i+1:]
+			return s
 		}
-		return false, err
 	}
-	if !found {
-		return false, nil
+	c.ok = false
+	return ""
+}
+
+// parseItemInfoEntry parses infe box and returns the item ID when it is a XMP item.
+func parseItemInfoEntry(data []byte) (uint32, bool) {
+	c := byteCursor{buf: data, ok: true}
+	version := c.readUint(1)
+	c.readUint(3) // flags
+
+	var itemID uint64
+	if version == 3 {
+		itemID = c.readUint(4)
+	} else {
+		itemID = c.readUint(2)
 	}
+	c.readUint(2) // item_protection_index
 
-	moovSize := moovhd.dataSize
+	if version >= 2 {
+		if itemType := c.readFourCC(); itemType != "mime" {
+			return 0, false
+		}
+	}
 
-	hasValidDuration := false
-	hasPictTrak := false
-	for {
+	c.readString() // item_name
+	contentType := c.readString()
+	return uint32(itemID), c.ok && contentType == xmpContentType
+}
+
+// findItemLocation parses iloc box and returns the location of the item.
+// Only an item stored in a single extent of the file is found.
+func findItemLocation(data []byte, targetItemID uint32) (itemLocationData, bool) {
+	c := byteCursor{buf: data, ok: true}
+	version := c.readUint(1)
+	c.readUint(3) // flags
+
+	sizes := c.readUint(1)
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0xf)
+	sizes = c.readUint(1)
+	baseOffsetSize, indexSize := int(sizes>>4), int(sizes&0xf)
+	if version == 0 {
+		indexSize = 0
+	}
+
+	var itemCount uint64
+	if version < 2 {
+		itemCount = c.readUint(2)
+	} else {
+		itemCount = c.readUint(4)
+	}
+
+	for i := uint64(0); i < itemCount && c.ok; i++ {
+		var itemID uint64
+		if version < 2 {
+			itemID = c.readUint(2)
+		} else {
+			itemID = c.readUint(4)
+		}
+
+		constructionMethod := uint64(0)
+		if version >= 1 {
+			constructionMethod = c.readUint(2) & 0xf
+		}
+		c.readUint(2) // data_reference_index
+		baseOffset := c.readUint(baseOffsetSize)
+
+		extentCount := c.readUint(2)
+		var extentOffset, extentLength uint64
+		for j := uint64(0); j < extentCount && c.ok; j++ {
+			c.readUint(indexSize) // extent_index
+			extentOffset = c.readUint(offsetSize)
+			extentLength = c.readUint(lengthSize)
+		}
+
+		if c.ok && uint32(itemID) == targetItemID {
+			if constructionMethod != 0 || extentCount != 1 {
+				return itemLocationData{}, false
+			}
+			return itemLocationData{
+				offset: int64(baseOffset + extentOffset),
+				length: int64(extentLength),
+			}, true
+		}
+	}
+	return itemLocationData{}, false
+}
+
+// decodeItemInfoBox reads iinf box and returns the ID of the XMP item.
+func (d *decoder) decodeItemInfoBox(dataSize int64) (uint32, bool, error) {
+	var version byte
+	if err := d.read(&version); err != nil {
+		return 0, false, err
+	}
+	entryCountSize := int64(2)
+	if version != 0 {
+		entryCountSize = 4
+	}
+	if err := d.Advance(3 + uint(entryCountSize)); err != nil { // flags, entry_count
+		return 0, false, err
+	}
+	dataSize -= 1 + 3 + entryCountSize
+
+	var xmpItemID uint32
+	found := false
+	for dataSize > 0 {
 		bhd, err := d.decodeBoxHeader()
 		if err != nil {
-			return false, err
+			return 0, false, err
 		}
-		moovSize -= 4 + 4 + bhd.dataSize
+		dataSize -= 4 + 4 + bhd.dataSize
+
+		if bhd.boxType != "infe" {
+			if err := d.Advance(uint(bhd.dataSize)); err != nil {
+				return 0, false, err
+			}
+			continue
+		}
+
+		var data []byte
+		if bhd.dataSize >= 0 && bhd.dataSize <= maxEntrySize {
+			data, err = d.Next(int(bhd.dataSize))
+		} else {
+			data, err = d.readBoxData(bhd.dataSize)
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		if itemID, ok := parseItemInfoEntry(data); ok && !found {
+			xmpItemID = itemID
+			found = true
+		}
+	}
+	return xmpItemID, found, nil
+}
+
+// decodeMetaBox reads meta box and returns the location of the XMP item.
+func (d *decoder) decodeMetaBox(dataSize int64) (*itemLocationData, error) {
+	err := d.Advance(
+		1 + // (FullBox) version
+			3, // (FullBox) flags
+	)
+	if err != nil {
+		return nil, err
+	}
+	dataSize -= 1 + 3
+
+	var xmpItemID uint32
+	hasXMPItem := false
+	var locationData []byte
+	for dataSize > 0 {
+		bhd, err := d.decodeBoxHeader()
+		if err != nil {
+			return nil, err
+		}
+		dataSize -= 4 + 4 + bhd.dataSize
 
 		switch bhd.boxType {
-		case "mvhd":
-			mhbd, err := d.decodeMovieHeaderBox(bhd.dataSize)
+		case "iinf":
+			if xmpItemID, hasXMPItem, err = d.decodeItemInfoBox(bhd.dataSize); err != nil {
+				return nil, err
+			}
+		case "iloc":
+			if bhd.dataSize >= 0 && bhd.dataSize <= maxEntrySize {
+				locationData = d.locationBuf[:bhd.dataSize]
+				_, err = d.ReadFull(locationData)
+			} else {
+				locationData, err = d.readBoxData(bhd.dataSize)
+			}
 			if err != nil {
-				return false, err
+				return nil, err
 			}
+		default:
+			if err := d.Advance(uint(bhd.dataSize)); err != nil {
+				return nil, err
+			}
+		}
+	}
 
-			hasValidDuration = mhbd.duration > 0
-			if !hasValidDuration {
-				return false, nil
+	if !hasXMPItem {
+		return nil, nil
+	}
+	if loc, ok := findItemLocation(locationData, xmpItemID); ok {
+		return &loc, nil
+	}
+	return nil, nil
+}
+
+func (d *decoder) decodeEmbeddedVideo(xmpLocation itemLocationData) (*midec.EmbeddedVideo, error) {
+	if err := d.SeekTo(xmpLocation.offset); err != nil {
+		return nil, err
+	}
+
+	packet, err := d.readBoxData(xmpLocation.length)
+	if err != nil || packet == nil {
+		return nil, err
+	}
+	return xmp.ParseMotionPhoto(packet), nil
+}
+
+// inspectBoxes reads the top-level boxes after ftyp box.
+// It returns Info as a value so that isAnimated does not allocate it.
+func (d *decoder) inspectBoxes(isAnimatable bool) (midec.Info, error) {
+	var xmpLocation *itemLocationData
+	var video *midec.EmbeddedVideo
+	for {
+		bhd, err := d.decodeBoxHeader()
+		if err != nil {
+			if err == io.EOF {
+				break
 			}
-		case "trak":
-			isPictureType, err := d.confirmTrackBoxIsPictureType(bhd.dataSize)
+			return midec.Info{}, err
+		}
+		if bhd.untilEnd {
+			break
+		}
+		if bhd.dataSize < 0 {
+			return midec.Info{}, io.ErrUnexpectedEOF
+		}
+
+		boxEnd := d.Offset() + bhd.dataSize
+		switch bhd.boxType {
+		case "meta":
+			if xmpLocation, err = d.decodeMetaBox(bhd.dataSize); err != nil {
+				return midec.Info{}, err
+			}
+		case "moov":
+			mbd, err := d.decodeMovieBox(bhd.dataSize)
 			if err != nil {
-				return false, err
+				return midec.Info{}, err
 			}
-
-			if isPictureType {
-				hasPictTrak = isPictureType
+			if isAnimatable && mbd.isAnimated() {
+				return midec.Info{Kind: midec.KindAnimated}, nil
+			}
+			// a movie with a video track, such as MP4 or QuickTime
+			if mbd.hasTrack("vide") {
+				return midec.Info{
+					Kind:     midec.KindVideo,
+					Duration: mbd.duration(),
+					Tracks:   len(mbd.handlerTypes),
+					HasAudio: mbd.hasTrack("soun"),
+				}, nil
 			}
 		default:
-			if err := d.Advance(uint(bhd.dataSize)); err != nil {
-				return false, err
+			// the XMP item is read while passing it, so that a reader without io.Seeker does not go backward
+			if xmpLocation != nil && d.Offset() <= xmpLocation.offset && xmpLocation.offset < boxEnd {
+				if video, err = d.decodeEmbeddedVideo(*xmpLocation); err != nil {
+					return midec.Info{}, err
+				}
+				xmpLocation = nil
 			}
 		}
-
-		if hasValidDuration && hasPictTrak {
-			return true, nil
+		if err := d.SeekTo(boxEnd); err != nil {
+			return midec.Info{}, err
 		}
-		if moovSize <= 0 {
-			return false, nil
+	}
+
+	if xmpLocation != nil {
+		var err error
+		video, err = d.decodeEmbeddedVideo(*xmpLocation)
+		// the XMP item before the current position cannot be read without io.Seeker
+		if err != nil && err != midec.ErrNotSeekable {
+			return midec.Info{}, err
 		}
 	}
+	if video != nil {
+		return midec.Info{Kind: midec.KindEmbeddedVideo, Video: video}, nil
+	}
+	return midec.Info{Kind: midec.KindStatic}, nil
 }
 
 func isAnimated(r io.Reader) (bool, error) {
-	d := decoder{*midec.NewReadAdvancer(r)}
-	return d.decode()
+	d := decoder{ReadAdvancer: *midec.NewReadAdvancer(r)}
+	isAnimatable, err := d.decodeFileTypeBox()
+	if err != nil || !isAnimatable {
+		return false, err
+	}
+
+	info, err := d.inspectBoxes(isAnimatable)
+	if err != nil {
+		return false, err
+	}
+	return info.Kind.IsMultiImage(), nil
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{ReadAdvancer: *midec.NewReadAdvancer(r)}
+	isAnimatable, err := d.decodeFileTypeBox()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := d.inspectBoxes(isAnimatable)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
 }
 
 func init() {
-	midec.RegisterFormat("isobmff", isobmmfHeader, isAnimated)
+	midec.RegisterInspectableFormat("isobmff", isobmmfHeader, isAnimated, inspect)
 }
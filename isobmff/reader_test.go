@@ -1,8 +1,13 @@
 package isobmff
 
 import (
+	"io"
 	"os"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/sapphi-red/midec"
 )
 
 const testdataFolder = "../testdata/isobmff/"
@@ -27,9 +32,13 @@ func Test_isAnimated(t *testing.T) {
 		{"static.avif", false, false},
 		{"static.heif", false, false},
 		{"movie.mp4", false, false},
+		{"movie-audio.mov", false, false},
+		{"motionphoto.heic", true, false},
 		{"invalid-filetypebox1.avif", false, true},
 		{"invalid-filetypebox2.avif", false, true},
 		{"invalid-filetypebox3.avif", false, true},
+		// a box with a largesize smaller than its header
+		{"invalid-largesize.avif", false, true},
 	}
 
 	for _, tc := range testcases {
@@ -47,3 +56,130 @@ func Test_isAnimated(t *testing.T) {
 		})
 	}
 }
+
+func Test_inspect_Video(t *testing.T) {
+	t.Parallel()
+
+	runInspect := func(filename string) (*midec.Info, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return inspect(fp)
+	}
+
+	testcases := []struct {
+		filename string
+		expected *midec.Info
+	}{
+		{"movie.mp4", &midec.Info{Kind: midec.KindVideo, Duration: 2040 * time.Millisecond, Tracks: 1}},
+		{"movie-audio.mov", &midec.Info{Kind: midec.KindVideo, Duration: 3 * time.Second, Tracks: 2, HasAudio: true}},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runInspect(tc.filename)
+			if actualErr != nil {
+				t.Fatalf("Error = %v; want HasError = false", actualErr)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Info = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_inspect(t *testing.T) {
+	t.Parallel()
+
+	runInspect := func(filename string) (*midec.Info, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return inspect(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expectedKind     midec.Kind
+		expectedVideo    *midec.EmbeddedVideo
+		expectedHasError bool
+	}{
+		{"animated.avif", midec.KindAnimated, nil, false},
+		{"static.avif", midec.KindStatic, nil, false},
+		{"static.heif", midec.KindStatic, nil, false},
+		{"static-xmp.heic", midec.KindStatic, nil, false},
+		{"motionphoto.heic", midec.KindEmbeddedVideo, &midec.EmbeddedVideo{OffsetFromEnd: 40, Length: 40}, false},
+		{"motionphoto-iloc1.heic", midec.KindEmbeddedVideo, &midec.EmbeddedVideo{OffsetFromEnd: 40, Length: 40}, false},
+		{"invalid-meta.heic", midec.KindStatic, nil, true},
+		{"invalid-filetypebox1.avif", midec.KindStatic, nil, true},
+		// a box with a largesize smaller than its header
+		{"invalid-largesize.avif", midec.KindStatic, nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runInspect(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if actual.Kind != tc.expectedKind {
+				t.Errorf("Kind = %v; want %v", actual.Kind, tc.expectedKind)
+			}
+			if !reflect.DeepEqual(actual.Video, tc.expectedVideo) {
+				t.Errorf("Video = %+v; want %+v", actual.Video, tc.expectedVideo)
+			}
+		})
+	}
+}
+
+func Test_inspect_NotSeekable(t *testing.T) {
+	t.Parallel()
+
+	runInspect := func(filename string) (*midec.Info, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		// hides io.Seeker
+		return inspect(struct{ io.Reader }{fp})
+	}
+
+	testcases := []struct {
+		filename     string
+		expectedKind midec.Kind
+	}{
+		{"static.heif", midec.KindStatic},
+		{"static-xmp.heic", midec.KindStatic},
+		{"motionphoto.heic", midec.KindEmbeddedVideo},
+		{"motionphoto-iloc1.heic", midec.KindEmbeddedVideo},
+		{"animated.avif", midec.KindAnimated},
+		{"movie.mp4", midec.KindVideo},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runInspect(tc.filename)
+			if actualErr != nil {
+				t.Fatalf("Error = %v; want HasError = false", actualErr)
+			}
+			if actual.Kind != tc.expectedKind {
+				t.Errorf("Kind = %v; want %v", actual.Kind, tc.expectedKind)
+			}
+		})
+	}
+}
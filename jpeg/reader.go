@@ -0,0 +1,329 @@
+// Package jpeg implements a JPEG MPO (Multi-Picture Format) and Motion Photo detector
+package jpeg
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/sapphi-red/midec"
+	"github.com/sapphi-red/midec/internal/xmp"
+)
+
+const jpegHeader = "\xff\xd8\xff"
+
+// ErrInvalidMarker indicates that detecting encountered an invalid marker.
+var ErrInvalidMarker = errors.New("midec: (jpeg) invalid marker")
+
+// ErrInvalidMPF indicates that the MP Index IFD in APP2 segment was broken.
+var ErrInvalidMPF = errors.New("midec: (jpeg) invalid MP index IFD")
+
+const (
+	markerSOI  = 0xd8
+	markerEOI  = 0xd9
+	markerSOS  = 0xda
+	markerAPP1 = 0xe1
+	markerAPP2 = 0xe2
+	markerTEM  = 0x01
+	markerRST0 = 0xd0
+	markerRST7 = 0xd7
+)
+
+const (
+	mpfIdentifier = "MPF\x00"
+	xmpIdentifier = "http://ns.adobe.com/xap/1.0/\x00"
+)
+
+const (
+	tagNumberOfImages = 0xb001
+	tagMPEntry        = 0xb002
+)
+
+const (
+	mpEntrySize = 16
+
+	maskMPType = 0xffffff
+)
+
+// MPType is the MP Type Code of an individual image.
+type MPType uint32
+
+// MP Type Codes defined in CIPA DC-007.
+const (
+	MPTypeUndefined            MPType = 0x000000
+	MPTypeLargeThumbnailVGA    MPType = 0x010001
+	MPTypeLargeThumbnailFullHD MPType = 0x010002
+	MPTypeMultiFramePanorama   MPType = 0x020001
+	MPTypeMultiFrameDisparity  MPType = 0x020002
+	MPTypeMultiFrameMultiAngle MPType = 0x020003
+	MPTypeBaselinePrimary      MPType = 0x030000
+)
+
+// IsLargeThumbnail reports whether t is a large thumbnail, which is a preview of the primary image.
+func (t MPType) IsLargeThumbnail() bool {
+	return t>>16 == 0x01
+}
+
+func (t MPType) String() string {
+	switch t {
+	case MPTypeLargeThumbnailVGA:
+		return "large thumbnail (VGA)"
+	case MPTypeLargeThumbnailFullHD:
+		return "large thumbnail (full HD)"
+	case MPTypeMultiFramePanorama:
+		return "multi-frame panorama"
+	case MPTypeMultiFrameDisparity:
+		return "multi-frame disparity"
+	case MPTypeMultiFrameMultiAngle:
+		return "multi-frame multi-angle"
+	case MPTypeBaselinePrimary:
+		return "baseline primary"
+	}
+	return "undefined"
+}
+
+// Image is an entry of the MP Index IFD.
+type Image struct {
+	Type MPType
+	Size uint32
+	// Offset is relative to the MP header. It is 0 for the first image.
+	Offset uint32
+}
+
+// MultiPicture is the information read from the MP Index IFD.
+type MultiPicture struct {
+	// Images is empty when the file was a plain JPEG.
+	Images []Image
+}
+
+type segmentHeaderData struct {
+	marker   byte
+	dataSize uint16
+}
+
+type decoder struct {
+	midec.ReadAdvancer
+}
+
+func (d *decoder) readOneByte() (byte, error) {
+	buf, err := d.Next(1)
+	if err != nil {
+		return 0, err
+	}
+	return buf[0], nil
+}
+
+func (d *decoder) skipHeader() error {
+	return d.Advance(2) // SOI
+}
+
+// decodeSegmentHeader reads the next marker and its length.
+// For markers without length, dataSize is 0.
+func (d *decoder) decodeSegmentHeader() (shd segmentHeaderData, err error) {
+	b, err := d.readOneByte()
+	if err != nil {
+		return
+	}
+	if b != 0xff {
+		err = ErrInvalidMarker
+		return
+	}
+
+	// skip fill bytes
+	for b == 0xff {
+		if b, err = d.readOneByte(); err != nil {
+			return
+		}
+	}
+	shd.marker = b
+
+	switch {
+	case b == 0x00:
+		err = ErrInvalidMarker
+		return
+	case b == markerSOI, b == markerEOI, b == markerTEM, markerRST0 <= b && b <= markerRST7:
+		return
+	}
+
+	var length uint16
+	if err = d.ReadBinary(binary.BigEndian, &length); err != nil {
+		return
+	}
+	if length < 2 {
+		err = ErrInvalidMarker
+		return
+	}
+	shd.dataSize = length - 2
+	return
+}
+
+// readIdentifiedSegment reads the segment data when it begins with identifier.
+// Otherwise it skips the segment and returns nil.
+func (d *decoder) readIdentifiedSegment(shd segmentHeaderData, identifier string) ([]byte, error) {
+	if int(shd.dataSize) < len(identifier) {
+		return nil, d.Advance(uint(shd.dataSize))
+	}
+
+	id, err := d.Next(len(identifier))
+	if err != nil {
+		return nil, err
+	}
+	size := int(shd.dataSize) - len(identifier)
+	if string(id) != identifier {
+		return nil, d.Advance(uint(size))
+	}
+
+	buf := make([]byte, size)
+	if _, err := d.ReadFull(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// parseMPIndexIFD parses the MP header in APP2 segment. data begins with the MP header (after "MPF\0").
+func parseMPIndexIFD(data []byte) (*MultiPicture, error) {
+	if len(data) < 8 {
+		return nil, ErrInvalidMPF
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return nil, ErrInvalidMPF
+	}
+
+	ifdOffset := uint64(order.Uint32(data[4:8]))
+	if ifdOffset+2 > uint64(len(data)) {
+		return nil, ErrInvalidMPF
+	}
+	count := uint64(order.Uint16(data[ifdOffset:]))
+	entriesStart := ifdOffset + 2
+	if entriesStart+count*12 > uint64(len(data)) {
+		return nil, ErrInvalidMPF
+	}
+
+	var numberOfImages uint32
+	var mpEntryCount, mpEntryOffset uint64
+	for i := uint64(0); i < count; i++ {
+		entry := data[entriesStart+i*12 : entriesStart+(i+1)*12]
+		tag := order.Uint16(entry[0:2])
+		valueCount := uint64(order.Uint32(entry[4:8]))
+
+		switch tag {
+		case tagNumberOfImages:
+			numberOfImages = order.Uint32(entry[8:12])
+		case tagMPEntry:
+			mpEntryCount = valueCount
+			mpEntryOffset = uint64(order.Uint32(entry[8:12]))
+		}
+	}
+
+	if mpEntryCount != uint64(numberOfImages)*mpEntrySize || mpEntryOffset+mpEntryCount > uint64(len(data)) {
+		return nil, ErrInvalidMPF
+	}
+
+	images := make([]Image, numberOfImages)
+	for i := range images {
+		entry := data[mpEntryOffset+uint64(i)*mpEntrySize:]
+		images[i] = Image{
+			Type:   MPType(order.Uint32(entry[0:4]) & maskMPType),
+			Size:   order.Uint32(entry[4:8]),
+			Offset: order.Uint32(entry[8:12]),
+		}
+	}
+	return &MultiPicture{Images: images}, nil
+}
+
+// decodeMetadata walks markers until SOS and reads the MP Index IFD and the XMP packet if exist.
+func (d *decoder) decodeMetadata() (*MultiPicture, *midec.EmbeddedVideo, error) {
+	if err := d.skipHeader(); err != nil {
+		return nil, nil, err
+	}
+
+	mp := &MultiPicture{}
+	var video *midec.EmbeddedVideo
+	for {
+		shd, err := d.decodeSegmentHeader()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch shd.marker {
+		case markerSOS, markerEOI:
+			return mp, video, nil
+		case markerAPP1:
+			data, err := d.readIdentifiedSegment(shd, xmpIdentifier)
+			if err != nil {
+				return nil, nil, err
+			}
+			if data != nil && video == nil {
+				video = xmp.ParseMotionPhoto(data)
+			}
+		case markerAPP2:
+			data, err := d.readIdentifiedSegment(shd, mpfIdentifier)
+			if err != nil {
+				return nil, nil, err
+			}
+			if data != nil {
+				if mp, err = parseMPIndexIFD(data); err != nil {
+					return nil, nil, err
+				}
+			}
+		default:
+			if err := d.Advance(uint(shd.dataSize)); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+}
+
+func (d *decoder) inspect() (*midec.Info, error) {
+	mp, video, err := d.decodeMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	if video != nil {
+		return &midec.Info{Kind: midec.KindEmbeddedVideo, Video: video}, nil
+	}
+
+	// large thumbnails are previews of the primary image, so they are not counted
+	count := 0
+	for _, img := range mp.Images {
+		if !img.Type.IsLargeThumbnail() {
+			count++
+		}
+	}
+	if count >= 2 {
+		return &midec.Info{Kind: midec.KindMultiImage, Frames: count}, nil
+	}
+	return &midec.Info{Kind: midec.KindStatic, Frames: 1}, nil
+}
+
+// DecodeMultiPicture reads the MP Index IFD in APP2 segment.
+func DecodeMultiPicture(r io.Reader) (*MultiPicture, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	mp, _, err := d.decodeMetadata()
+	return mp, err
+}
+
+func isAnimated(r io.Reader) (bool, error) {
+	info, err := inspect(r)
+	if err != nil {
+		return false, err
+	}
+	return info.Kind.IsMultiImage(), nil
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.inspect()
+}
+
+func init() {
+	midec.RegisterInspectableFormat("jpeg", jpegHeader, isAnimated, inspect)
+}
@@ -0,0 +1,159 @@
+package jpeg
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sapphi-red/midec"
+)
+
+const testdataFolder = "../testdata/jpeg/"
+
+func Test_isAnimated(t *testing.T) {
+	t.Parallel()
+
+	runIsAnimated := func(filename string) (bool, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return isAnimated(fp)
+	}
+
+	testcases := []struct {
+		filename           string
+		expectedIsAnimated bool
+		expectedHasError   bool
+	}{
+		{"multi-disparity.mpo", true, false},
+		{"multi-angle.mpo", true, false},
+		{"motionphoto.jpg", true, false},
+		{"microvideo.jpg", true, false},
+		{"static.jpg", false, false},
+		{"static-icc.jpg", false, false},
+		{"static-thumbnail.jpg", false, false},
+		{"invalid-marker.jpg", false, true},
+		{"invalid-segment.jpg", false, true},
+		{"invalid-mpf.mpo", false, true},
+		{"invalid-mpf-entry.mpo", false, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actualIsAnimated, actualErr := runIsAnimated(tc.filename)
+			if tc.expectedIsAnimated != actualIsAnimated {
+				t.Errorf("IsAnimated = %t; want %t", actualIsAnimated, tc.expectedIsAnimated)
+			}
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+		})
+	}
+}
+
+func Test_DecodeMultiPicture(t *testing.T) {
+	t.Parallel()
+
+	runDecodeMultiPicture := func(filename string) (*MultiPicture, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return DecodeMultiPicture(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expected         *MultiPicture
+		expectedHasError bool
+	}{
+		{"multi-disparity.mpo", &MultiPicture{Images: []Image{
+			{Type: MPTypeMultiFrameDisparity, Size: 1000, Offset: 0},
+			{Type: MPTypeMultiFrameDisparity, Size: 1000, Offset: 2000},
+		}}, false},
+		{"static-thumbnail.jpg", &MultiPicture{Images: []Image{
+			{Type: MPTypeBaselinePrimary, Size: 1000, Offset: 0},
+			{Type: MPTypeLargeThumbnailVGA, Size: 1000, Offset: 2000},
+		}}, false},
+		{"static.jpg", &MultiPicture{}, false},
+		{"invalid-mpf.mpo", nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runDecodeMultiPicture(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("MultiPicture = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_inspect(t *testing.T) {
+	t.Parallel()
+
+	runInspect := func(filename string) (*midec.Info, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return inspect(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expectedKind     midec.Kind
+		expectedVideo    *midec.EmbeddedVideo
+		expectedFrames   int
+		expectedHasError bool
+	}{
+		{"motionphoto.jpg", midec.KindEmbeddedVideo, &midec.EmbeddedVideo{OffsetFromEnd: 40, Length: 40}, 0, false},
+		{"microvideo.jpg", midec.KindEmbeddedVideo, &midec.EmbeddedVideo{OffsetFromEnd: 40, Length: 40}, 0, false},
+		{"multi-disparity.mpo", midec.KindMultiImage, nil, 2, false},
+		{"static.jpg", midec.KindStatic, nil, 1, false},
+		{"static-thumbnail.jpg", midec.KindStatic, nil, 1, false},
+		{"static-motionphoto-off.jpg", midec.KindStatic, nil, 1, false},
+		{"static-xmp.jpg", midec.KindStatic, nil, 1, false},
+		{"static-xmp-broken.jpg", midec.KindStatic, nil, 1, false},
+		{"invalid-marker.jpg", midec.KindStatic, nil, 0, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runInspect(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if actual.Kind != tc.expectedKind {
+				t.Errorf("Kind = %v; want %v", actual.Kind, tc.expectedKind)
+			}
+			if !reflect.DeepEqual(actual.Video, tc.expectedVideo) {
+				t.Errorf("Video = %+v; want %+v", actual.Video, tc.expectedVideo)
+			}
+			if actual.Frames != tc.expectedFrames {
+				t.Errorf("Frames = %d; want %d", actual.Frames, tc.expectedFrames)
+			}
+		})
+	}
+}
@@ -0,0 +1,456 @@
+// Package jxl implements a Animated JPEG XL detector
+package jxl
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/sapphi-red/midec"
+	"github.com/sapphi-red/midec/internal/fourcc"
+)
+
+const (
+	codestreamHeader = "\xff\x0a"
+	containerHeader  = "\x00\x00\x00\x0cJXL \x0d\x0a\x87\x0a"
+)
+
+// ErrNoCodestream indicates that the container did not have jxlc nor jxlp box.
+var ErrNoCodestream = errors.New("midec: (jxl) codestream box not found")
+
+// u32Distribution is one of the four distributions selectable in a U32 field.
+// When bits is 0, offset is used as the value as it is.
+type u32Distribution struct {
+	bits   uint
+	offset uint32
+}
+
+type u32Distributions [4]u32Distribution
+
+var (
+	sizeHeaderSizeDist = u32Distributions{{9, 1}, {13, 1}, {18, 1}, {30, 1}}
+
+	previewHeaderSizeDiv8Dist = u32Distributions{{0, 16}, {0, 32}, {5, 1}, {9, 33}}
+	previewHeaderSizeDist     = u32Distributions{{6, 1}, {8, 65}, {10, 321}, {12, 1345}}
+
+	animationHeaderTpsNumeratorDist   = u32Distributions{{0, 100}, {0, 1000}, {10, 1}, {30, 1}}
+	animationHeaderTpsDenominatorDist = u32Distributions{{0, 1}, {0, 1001}, {8, 1}, {10, 1}}
+	animationHeaderNumLoopsDist       = u32Distributions{{0, 0}, {3, 0}, {16, 0}, {32, 0}}
+)
+
+// sizeHeaderRatios is xsize / ysize of each ratio of SizeHeader, from 1.
+var sizeHeaderRatios = [7][2]uint64{{1, 1}, {12, 10}, {4, 3}, {3, 2}, {16, 9}, {5, 4}, {2, 1}}
+
+type animationHeaderData struct {
+	tpsNumerator   uint32
+	tpsDenominator uint32
+	numLoops       uint32
+}
+
+type imageHeaderData struct {
+	width, height uint32
+	hasAnimation  bool
+	animation     animationHeaderData
+}
+
+type boxHeaderData struct {
+	dataSize int64
+	untilEnd bool
+	boxType  string
+}
+
+// bitReader reads the codestream from the least significant bit of each byte.
+type bitReader struct {
+	r     *midec.ReadAdvancer
+	cur   byte
+	nbits uint
+}
+
+func (br *bitReader) readBits(n uint) (uint32, error) {
+	var v uint32
+	for i := uint(0); i < n; i++ {
+		if br.nbits == 0 {
+			b, err := br.r.Next(1)
+			if err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return 0, err
+			}
+			br.cur = b[0]
+			br.nbits = 8
+		}
+
+		v |= uint32(br.cur&1) << i
+		br.cur >>= 1
+		br.nbits--
+	}
+	return v, nil
+}
+
+func (br *bitReader) readBool() (bool, error) {
+	v, err := br.readBits(1)
+	return v == 1, err
+}
+
+func (br *bitReader) readU32(dists u32Distributions) (uint32, error) {
+	selector, err := br.readBits(2)
+	if err != nil {
+		return 0, err
+	}
+
+	dist := dists[selector]
+	if dist.bits == 0 {
+		return dist.offset, nil
+	}
+
+	v, err := br.readBits(dist.bits)
+	if err != nil {
+		return 0, err
+	}
+	return v + dist.offset, nil
+}
+
+// decodeSizeDimension reads ysize or xsize of SizeHeader.
+func (br *bitReader) decodeSizeDimension(small bool) (uint32, error) {
+	if !small {
+		return br.readU32(sizeHeaderSizeDist)
+	}
+	div8, err := br.readBits(5) // size_div8_minus_1
+	if err != nil {
+		return 0, err
+	}
+	return (div8 + 1) * 8, nil
+}
+
+func (br *bitReader) decodeSizeHeader() (width, height uint32, err error) {
+	small, err := br.readBool()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if height, err = br.decodeSizeDimension(small); err != nil {
+		return 0, 0, err
+	}
+
+	ratio, err := br.readBits(3)
+	if err != nil {
+		return 0, 0, err
+	}
+	if ratio != 0 {
+		r := sizeHeaderRatios[ratio-1]
+		return uint32(uint64(height) * r[0] / r[1]), height, nil
+	}
+
+	if width, err = br.decodeSizeDimension(small); err != nil {
+		return 0, 0, err
+	}
+	return width, height, nil
+}
+
+func (br *bitReader) skipPreviewHeader() error {
+	div8, err := br.readBool()
+	if err != nil {
+		return err
+	}
+
+	dist := previewHeaderSizeDist
+	if div8 {
+		dist = previewHeaderSizeDiv8Dist
+	}
+
+	if _, err := br.readU32(dist); err != nil { // ysize
+		return err
+	}
+
+	ratio, err := br.readBits(3)
+	if err != nil {
+		return err
+	}
+	if ratio != 0 {
+		return nil
+	}
+
+	_, err = br.readU32(dist) // xsize
+	return err
+}
+
+func (br *bitReader) decodeAnimationHeader() (ahd animationHeaderData, err error) {
+	if ahd.tpsNumerator, err = br.readU32(animationHeaderTpsNumeratorDist); err != nil {
+		return
+	}
+	if ahd.tpsDenominator, err = br.readU32(animationHeaderTpsDenominatorDist); err != nil {
+		return
+	}
+	if ahd.numLoops, err = br.readU32(animationHeaderNumLoopsDist); err != nil {
+		return
+	}
+	return
+}
+
+// decodeImageHeader reads the codestream until the AnimationHeader in the ImageMetadata.
+// The signature must be already skipped.
+func (br *bitReader) decodeImageHeader() (imageHeaderData, error) {
+	var ihd imageHeaderData
+	var err error
+	if ihd.width, ihd.height, err = br.decodeSizeHeader(); err != nil {
+		return imageHeaderData{}, err
+	}
+
+	allDefault, err := br.readBool()
+	if err != nil {
+		return imageHeaderData{}, err
+	}
+	if allDefault {
+		return ihd, nil
+	}
+
+	extraFields, err := br.readBool()
+	if err != nil {
+		return imageHeaderData{}, err
+	}
+	if !extraFields {
+		return ihd, nil
+	}
+
+	if _, err := br.readBits(3); err != nil { // orientation_minus_1
+		return imageHeaderData{}, err
+	}
+
+	haveIntrSize, err := br.readBool()
+	if err != nil {
+		return imageHeaderData{}, err
+	}
+	if haveIntrSize {
+		if _, _, err := br.decodeSizeHeader(); err != nil {
+			return imageHeaderData{}, err
+		}
+	}
+
+	havePreview, err := br.readBool()
+	if err != nil {
+		return imageHeaderData{}, err
+	}
+	if havePreview {
+		if err := br.skipPreviewHeader(); err != nil {
+			return imageHeaderData{}, err
+		}
+	}
+
+	if ihd.hasAnimation, err = br.readBool(); err != nil {
+		return imageHeaderData{}, err
+	}
+	if !ihd.hasAnimation {
+		return ihd, nil
+	}
+
+	if ihd.animation, err = br.decodeAnimationHeader(); err != nil {
+		return imageHeaderData{}, err
+	}
+	return ihd, nil
+}
+
+type decoder struct {
+	midec.ReadAdvancer
+}
+
+func (d *decoder) read(data interface{}) error {
+	return d.ReadBinary(binary.BigEndian, data)
+}
+
+func (d *decoder) decodeBoxHeader() (bhd boxHeaderData, err error) {
+	var size uint32
+	err = d.read(&size)
+	if err != nil {
+		return
+	}
+
+	boxTypeBuf, err := d.Next(4)
+	if err != nil {
+		return
+	}
+
+	boxType := fourcc.String(boxTypeBuf)
+
+	if size == 0 {
+		return boxHeaderData{
+			dataSize: 0,
+			untilEnd: true,
+			boxType:  boxType,
+		}, nil
+	}
+
+	if size != 1 {
+		return boxHeaderData{
+			dataSize: int64(size) - 4 - 4,
+			untilEnd: false,
+			boxType:  boxType,
+		}, nil
+	}
+
+	var largeSize int64
+	err = d.read(&largeSize)
+	if err != nil {
+		return
+	}
+
+	return boxHeaderData{
+		dataSize: largeSize - 4 - 4 - 8,
+		untilEnd: false,
+		boxType:  boxType,
+	}, nil
+}
+
+// findCodestreamBox skips boxes until jxlc or jxlp box.
+// When jxlp box is found, its index field is skipped.
+func (d *decoder) findCodestreamBox() (boxHeaderData, error) {
+	for {
+		bhd, err := d.decodeBoxHeader()
+		if err != nil {
+			if err == io.EOF {
+				return boxHeaderData{}, ErrNoCodestream
+			}
+			return boxHeaderData{}, err
+		}
+
+		switch bhd.boxType {
+		case "jxlc":
+			return bhd, nil
+		case "jxlp":
+			if err := d.Advance(4); err != nil { // index
+				return boxHeaderData{}, err
+			}
+			bhd.dataSize -= 4
+			return bhd, nil
+		}
+
+		if bhd.untilEnd {
+			return boxHeaderData{}, ErrNoCodestream
+		}
+		if bhd.dataSize < 0 {
+			return boxHeaderData{}, io.ErrUnexpectedEOF
+		}
+		if err := d.Advance(uint(bhd.dataSize)); err != nil {
+			return boxHeaderData{}, err
+		}
+	}
+}
+
+// codestreamReader reads the codestream stored in jxlc box or split into jxlp boxes.
+type codestreamReader struct {
+	d         *decoder
+	remaining int64
+	untilEnd  bool
+}
+
+func (cr *codestreamReader) Read(p []byte) (int, error) {
+	for !cr.untilEnd && cr.remaining <= 0 {
+		bhd, err := cr.d.findCodestreamBox()
+		if err != nil {
+			return 0, err
+		}
+		cr.remaining = bhd.dataSize
+		cr.untilEnd = bhd.untilEnd
+	}
+
+	if !cr.untilEnd && int64(len(p)) > cr.remaining {
+		p = p[:cr.remaining]
+	}
+	n, err := cr.d.Read(p)
+	cr.remaining -= int64(n)
+	return n, err
+}
+
+func (d *decoder) skipSignatureBox() error {
+	return d.Advance(uint(len(containerHeader)))
+}
+
+func (d *decoder) skipSignature() error {
+	return d.Advance(uint(len(codestreamHeader)))
+}
+
+func (d *decoder) decodeHeader() (imageHeaderData, error) {
+	if err := d.skipSignature(); err != nil {
+		return imageHeaderData{}, err
+	}
+
+	br := bitReader{r: &d.ReadAdvancer}
+	return br.decodeImageHeader()
+}
+
+func (d *decoder) decode() (bool, error) {
+	ihd, err := d.decodeHeader()
+	return ihd.hasAnimation, err
+}
+
+func (d *decoder) inspect() (*midec.Info, error) {
+	ihd, err := d.decodeHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &midec.Info{Kind: midec.KindStatic, Width: int(ihd.width), Height: int(ihd.height)}
+	if !ihd.hasAnimation {
+		return info, nil
+	}
+
+	ahd := ihd.animation
+	info.Kind = midec.KindAnimated
+	info.Loops = midec.LoopInfinite
+	if ahd.numLoops > 0 {
+		info.Loops = int(ahd.numLoops)
+	}
+	if ahd.tpsDenominator > 0 {
+		info.TicksPerSecond = float64(ahd.tpsNumerator) / float64(ahd.tpsDenominator)
+	}
+	return info, nil
+}
+
+// codestreamDecoder returns a decoder which reads the codestream in the container.
+func (d *decoder) codestreamDecoder() (*decoder, error) {
+	if err := d.skipSignatureBox(); err != nil {
+		return nil, err
+	}
+	return &decoder{*midec.NewReadAdvancer(&codestreamReader{d: d})}, nil
+}
+
+func (d *decoder) decodeContainer() (bool, error) {
+	cd, err := d.codestreamDecoder()
+	if err != nil {
+		return false, err
+	}
+	return cd.decode()
+}
+
+func (d *decoder) inspectContainer() (*midec.Info, error) {
+	cd, err := d.codestreamDecoder()
+	if err != nil {
+		return nil, err
+	}
+	return cd.inspect()
+}
+
+func isAnimated(r io.Reader) (bool, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.decode()
+}
+
+func isAnimatedContainer(r io.Reader) (bool, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.decodeContainer()
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.inspect()
+}
+
+func inspectContainer(r io.Reader) (*midec.Info, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.inspectContainer()
+}
+
+func init() {
+	midec.RegisterInspectableFormat("jxl", codestreamHeader, isAnimated, inspect)
+	midec.RegisterInspectableFormat("jxl", containerHeader, isAnimatedContainer, inspectContainer)
+}
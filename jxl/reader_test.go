@@ -0,0 +1,143 @@
+package jxl
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sapphi-red/midec"
+)
+
+const testdataFolder = "../testdata/jxl/"
+
+func Test_isAnimated(t *testing.T) {
+	t.Parallel()
+
+	runIsAnimated := func(filename string, isAnimated func(io.Reader) (bool, error)) (bool, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return isAnimated(fp)
+	}
+
+	testcases := []struct {
+		filename           string
+		isAnimated         func(io.Reader) (bool, error)
+		expectedIsAnimated bool
+		expectedHasError   bool
+	}{
+		{"animated.jxl", isAnimated, true, false},
+		{"animated-preview.jxl", isAnimated, true, false},
+		{"static.jxl", isAnimated, false, false},
+		{"static-extrafields.jxl", isAnimated, false, false},
+		{"invalid-header.jxl", isAnimated, false, true},
+		{"animated-jxlc.jxl", isAnimatedContainer, true, false},
+		{"animated-jxlp.jxl", isAnimatedContainer, true, false},
+		{"animated-jxlc-untilend.jxl", isAnimatedContainer, true, false},
+		{"static-jxlc.jxl", isAnimatedContainer, false, false},
+		{"invalid-nocodestream.jxl", isAnimatedContainer, false, true},
+		{"invalid-box-header.jxl", isAnimatedContainer, false, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actualIsAnimated, actualErr := runIsAnimated(tc.filename, tc.isAnimated)
+			if tc.expectedIsAnimated != actualIsAnimated {
+				t.Errorf("IsAnimated = %t; want %t", actualIsAnimated, tc.expectedIsAnimated)
+			}
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+		})
+	}
+}
+
+func Test_inspect(t *testing.T) {
+	t.Parallel()
+
+	runInspect := func(filename string, inspect func(io.Reader) (*midec.Info, error)) (*midec.Info, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return inspect(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		inspect          func(io.Reader) (*midec.Info, error)
+		expected         *midec.Info
+		expectedHasError bool
+	}{
+		{"animated.jxl", inspect, &midec.Info{Kind: midec.KindAnimated, Loops: midec.LoopInfinite, Width: 64, Height: 64, TicksPerSecond: 100}, false},
+		{"static.jxl", inspect, &midec.Info{Kind: midec.KindStatic, Width: 64, Height: 64}, false},
+		{"animated-jxlp.jxl", inspectContainer, &midec.Info{Kind: midec.KindAnimated, Loops: midec.LoopInfinite, Width: 64, Height: 64, TicksPerSecond: 100}, false},
+		{"static-jxlc.jxl", inspectContainer, &midec.Info{Kind: midec.KindStatic, Width: 64, Height: 64}, false},
+		{"invalid-header.jxl", inspect, nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runInspect(tc.filename, tc.inspect)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Info = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_decodeHeader_Offset(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		filename    string
+		isContainer bool
+	}{
+		{"animated.jxl", false},
+		{"static.jxl", false},
+		{"animated-jxlp.jxl", true},
+		{"static-jxlc.jxl", true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(testdataFolder + tc.filename)
+			if err != nil {
+				panic(err)
+			}
+			r := bytes.NewReader(data)
+
+			d := decoder{*midec.NewReadAdvancer(r)}
+			cd := &d
+			if tc.isContainer {
+				if cd, err = d.codestreamDecoder(); err != nil {
+					t.Fatalf("Error = %v; want HasError = false", err)
+				}
+			}
+			if _, err := cd.decodeHeader(); err != nil {
+				t.Fatalf("Error = %v; want HasError = false", err)
+			}
+
+			// the codestream is read through the ReadAdvancer, so the offset matches the bytes read
+			expected := int64(len(data) - r.Len())
+			if actual := d.Offset(); actual != expected {
+				t.Errorf("Offset = %d; want %d", actual, expected)
+			}
+		})
+	}
+}
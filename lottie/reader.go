@@ -0,0 +1,327 @@
+// Package lottie implements a Lottie (Bodymovin JSON) and dotLottie bundle detector
+package lottie
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/sapphi-red/midec"
+)
+
+const zipHeader = "PK\x03\x04"
+
+const manifestName = "manifest.json"
+
+// ErrNotLottie indicates that the JSON did not have the properties required for Lottie.
+var ErrNotLottie = errors.New("midec: (lottie) not a Lottie animation")
+
+// ErrInvalidBundle indicates that the dotLottie bundle did not have a valid manifest or animation entries.
+var ErrInvalidBundle = errors.New("midec: (lottie) invalid dotLottie bundle")
+
+// maxManifestSize is the maximum size of manifest.json to be read into memory.
+const maxManifestSize = 1 << 20
+
+// animationDirs are the directories of animations in a dotLottie bundle, for v1 and v2.
+var animationDirs = []string{"animations/", "a/"}
+
+// Animation is the information read from the top-level properties of a Lottie JSON.
+type Animation struct {
+	// ID is the animation ID in the manifest. It is only set for dotLottie bundles.
+	ID        string
+	Version   string
+	FrameRate float64
+	// InPoint and OutPoint are the first and the last frame.
+	InPoint  float64
+	OutPoint float64
+	Width    int
+	Height   int
+	// Layers is the number of top-level layers.
+	Layers int
+}
+
+// Frames returns the number of frames.
+func (a *Animation) Frames() float64 {
+	return a.OutPoint - a.InPoint
+}
+
+// Duration returns the time taken by one cycle.
+func (a *Animation) Duration() time.Duration {
+	if a.FrameRate <= 0 {
+		return 0
+	}
+	return time.Duration(a.Frames() / a.FrameRate * float64(time.Second))
+}
+
+func (a *Animation) isAnimated() bool {
+	return a.Frames() > 1
+}
+
+// Bundle is the information read from a dotLottie bundle.
+type Bundle struct {
+	// Animations is in the order of the manifest.
+	Animations []Animation
+}
+
+type manifestData struct {
+	Animations []struct {
+		ID string `json:"id"`
+	} `json:"animations"`
+}
+
+// sniffJSON reports whether b looks like the beginning of a Lottie JSON.
+func sniffJSON(b []byte) bool {
+	b = bytes.TrimLeft(b, " \t\r\n")
+	if !bytes.HasPrefix(b, []byte("{")) || !bytes.Contains(b, []byte(`"v"`)) {
+		return false
+	}
+	for _, key := range []string{`"fr"`, `"ip"`, `"op"`, `"layers"`} {
+		if bytes.Contains(b, []byte(key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffBundle reports whether b looks like the beginning of a dotLottie bundle.
+func sniffBundle(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(zipHeader)) && bytes.Contains(b, []byte(manifestName))
+}
+
+type decoder struct {
+	*json.Decoder
+}
+
+func (d decoder) expectDelim(delim json.Delim) error {
+	token, err := d.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return ErrNotLottie
+	}
+	return nil
+}
+
+// skipValue skips the next value without keeping it in memory.
+func (d decoder) skipValue() error {
+	depth := 0
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// countElements counts the elements of the next array.
+func (d decoder) countElements() (int, error) {
+	if err := d.expectDelim('['); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for d.More() {
+		if err := d.skipValue(); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, d.expectDelim(']')
+}
+
+func (d decoder) decodeAnimation() (*Animation, error) {
+	if err := d.expectDelim('{'); err != nil {
+		return nil, err
+	}
+
+	a := &Animation{}
+	found := make(map[string]bool)
+	for d.More() {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+
+		var width, height float64
+		switch key {
+		case "v":
+			err = d.Decode(&a.Version)
+		case "fr":
+			err = d.Decode(&a.FrameRate)
+		case "ip":
+			err = d.Decode(&a.InPoint)
+		case "op":
+			err = d.Decode(&a.OutPoint)
+		case "w":
+			err = d.Decode(&width)
+			a.Width = int(width)
+		case "h":
+			err = d.Decode(&height)
+			a.Height = int(height)
+		case "layers":
+			a.Layers, err = d.countElements()
+		default:
+			err = d.skipValue()
+		}
+		if err != nil {
+			if _, ok := err.(*json.UnmarshalTypeError); ok {
+				err = ErrNotLottie
+			}
+			return nil, err
+		}
+		found[key] = true
+	}
+
+	for _, key := range []string{"v", "fr", "ip", "op", "layers"} {
+		if !found[key] {
+			return nil, ErrNotLottie
+		}
+	}
+	return a, nil
+}
+
+// DecodeAnimation reads the top-level properties of a Lottie JSON.
+func DecodeAnimation(r io.Reader) (*Animation, error) {
+	return decoder{json.NewDecoder(r)}.decodeAnimation()
+}
+
+func openFile(zr *zip.Reader, name string) *zip.File {
+	for _, f := range zr.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func decodeManifest(zr *zip.Reader) (*manifestData, error) {
+	f := openFile(zr, manifestName)
+	if f == nil || f.UncompressedSize64 > maxManifestSize {
+		return nil, ErrInvalidBundle
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var manifest manifestData
+	if err := json.NewDecoder(io.LimitReader(rc, maxManifestSize)).Decode(&manifest); err != nil {
+		return nil, ErrInvalidBundle
+	}
+	if len(manifest.Animations) == 0 {
+		return nil, ErrInvalidBundle
+	}
+	return &manifest, nil
+}
+
+func decodeBundledAnimation(zr *zip.Reader, id string) (*Animation, error) {
+	var f *zip.File
+	for _, dir := range animationDirs {
+		if f = openFile(zr, dir+id+".json"); f != nil {
+			break
+		}
+	}
+	if f == nil {
+		return nil, ErrInvalidBundle
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	a, err := DecodeAnimation(rc)
+	if err != nil {
+		return nil, err
+	}
+	a.ID = id
+	return a, nil
+}
+
+// DecodeBundle reads manifest.json and the animations listed in it from a dotLottie bundle.
+func DecodeBundle(r io.ReaderAt, size int64) (*Bundle, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := decodeManifest(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &Bundle{}
+	for _, entry := range manifest.Animations {
+		a, err := decodeBundledAnimation(zr, entry.ID)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Animations = append(bundle.Animations, *a)
+	}
+	return bundle, nil
+}
+
+func inspectAnimations(animations []Animation) *midec.Info {
+	info := &midec.Info{Kind: midec.KindStatic}
+	if len(animations) > 0 {
+		info.Width = animations[0].Width
+		info.Height = animations[0].Height
+	}
+	for _, a := range animations {
+		if frames := int(a.Frames()); frames > info.Frames {
+			info.Frames = frames
+		}
+		if !a.isAnimated() {
+			continue
+		}
+		info.Kind = midec.KindAnimated
+		if duration := a.Duration(); duration > info.Duration {
+			info.Duration = duration
+		}
+	}
+	return info
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	a, err := DecodeAnimation(r)
+	if err != nil {
+		return nil, err
+	}
+	return inspectAnimations([]Animation{*a}), nil
+}
+
+func inspectBundle(r io.Reader) (*midec.Info, error) {
+	ra, size, err := midec.AsReaderAt(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle, err := DecodeBundle(ra, size)
+	if err != nil {
+		return nil, err
+	}
+	return inspectAnimations(bundle.Animations), nil
+}
+
+func init() {
+	midec.RegisterSniffedFormat("lottie", sniffJSON, nil, inspect)
+	midec.RegisterSniffedFormat("dotlottie", sniffBundle, nil, inspectBundle)
+}
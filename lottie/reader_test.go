@@ -0,0 +1,108 @@
+package lottie
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sapphi-red/midec"
+)
+
+const testdataFolder = "../testdata/lottie/"
+
+func Test_inspect(t *testing.T) {
+	t.Parallel()
+
+	runInspect := func(filename string, inspect func(*os.File) (*midec.Info, error)) (*midec.Info, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return inspect(fp)
+	}
+	json := func(fp *os.File) (*midec.Info, error) { return inspect(fp) }
+	bundle := func(fp *os.File) (*midec.Info, error) { return inspectBundle(fp) }
+
+	testcases := []struct {
+		filename         string
+		inspect          func(*os.File) (*midec.Info, error)
+		expected         *midec.Info
+		expectedHasError bool
+	}{
+		{"animated.json", json, &midec.Info{Kind: midec.KindAnimated, Frames: 60, Width: 512, Height: 512, Duration: 2 * time.Second}, false},
+		{"static.json", json, &midec.Info{Kind: midec.KindStatic, Frames: 1, Width: 64, Height: 64}, false},
+		{"invalid-nolayers.json", json, nil, true},
+		{"invalid-type.json", json, nil, true},
+		{"invalid-syntax.json", json, nil, true},
+		{"animated.lottie", bundle, &midec.Info{Kind: midec.KindAnimated, Frames: 50, Width: 512, Height: 512, Duration: 2 * time.Second}, false},
+		{"animated-v2.lottie", bundle, &midec.Info{Kind: midec.KindAnimated, Frames: 90, Width: 512, Height: 512, Duration: 3 * time.Second}, false},
+		{"invalid-missing.lottie", bundle, nil, true},
+		{"animated.json", bundle, nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runInspect(tc.filename, tc.inspect)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Info = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_DecodeBundle(t *testing.T) {
+	t.Parallel()
+
+	fp, err := os.Open(testdataFolder + "animated.lottie")
+	if err != nil {
+		panic(err)
+	}
+	stat, err := fp.Stat()
+	if err != nil {
+		panic(err)
+	}
+
+	actual, err := DecodeBundle(fp, stat.Size())
+	if err != nil {
+		t.Fatalf("Error = %v; want HasError = false", err)
+	}
+
+	expected := &Bundle{Animations: []Animation{
+		{ID: "still", Version: "5.7.4", FrameRate: 60, InPoint: 0, OutPoint: 1, Width: 512, Height: 512, Layers: 2},
+		{ID: "wave", Version: "5.7.4", FrameRate: 25, InPoint: 10, OutPoint: 60, Width: 512, Height: 512, Layers: 2},
+	}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Bundle = %+v; want %+v", actual, expected)
+	}
+}
+
+func Test_sniffJSON(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		input    string
+		expected bool
+	}{
+		{`{"v":"5.7.4","fr":30,"ip":0,"op":60}`, true},
+		{"\n {\"nm\":\"x\",\"v\":\"5.7.4\",\"layers\":[]}", true},
+		{`{"v":"1.0"}`, false},
+		{`["v","fr"]`, false},
+	}
+
+	for _, tc := range testcases {
+		if actual := sniffJSON([]byte(tc.input)); actual != tc.expected {
+			t.Errorf("sniffJSON(%q) = %t; want %t", tc.input, actual, tc.expected)
+		}
+	}
+}
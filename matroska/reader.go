@@ -0,0 +1,437 @@
+// Package matroska implements a Matroska / WebM detector
+package matroska
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	"github.com/sapphi-red/midec"
+)
+
+const ebmlHeader = "\x1a\x45\xdf\xa3"
+
+// ErrInvalidElement indicates that detecting encountered a broken element header.
+var ErrInvalidElement = errors.New("midec: (matroska) invalid element")
+
+// ErrUnknownDocType indicates that the EBML document was neither Matroska nor WebM.
+var ErrUnknownDocType = errors.New("midec: (matroska) unknown DocType")
+
+const (
+	idEBML           = 0x1a45dfa3
+	idDocType        = 0x4282
+	idSegment        = 0x18538067
+	idInfo           = 0x1549a966
+	idTimestampScale = 0x2ad7b1
+	idDuration       = 0x4489
+	idTracks         = 0x1654ae6b
+	idTrackEntry     = 0xae
+	idTrackNumber    = 0xd7
+	idTrackType      = 0x83
+	idCodecID        = 0x86
+	idCluster        = 0x1f43b675
+)
+
+const defaultTimestampScale = 1000000 // ns
+
+// maxStringSize is the maximum size of a string element to be read into memory.
+const maxStringSize = 1024
+
+// TrackType is the type of a track.
+type TrackType uint64
+
+// Track types defined in the Matroska specification.
+const (
+	TrackTypeVideo    TrackType = 1
+	TrackTypeAudio    TrackType = 2
+	TrackTypeComplex  TrackType = 3
+	TrackTypeLogo     TrackType = 0x10
+	TrackTypeSubtitle TrackType = 0x11
+	TrackTypeButtons  TrackType = 0x12
+	TrackTypeControl  TrackType = 0x20
+	TrackTypeMetadata TrackType = 0x21
+)
+
+func (t TrackType) String() string {
+	switch t {
+	case TrackTypeVideo:
+		return "video"
+	case TrackTypeAudio:
+		return "audio"
+	case TrackTypeComplex:
+		return "complex"
+	case TrackTypeLogo:
+		return "logo"
+	case TrackTypeSubtitle:
+		return "subtitle"
+	case TrackTypeButtons:
+		return "buttons"
+	case TrackTypeControl:
+		return "control"
+	case TrackTypeMetadata:
+		return "metadata"
+	}
+	return "unknown"
+}
+
+// Track is a TrackEntry element.
+type Track struct {
+	Number  uint64
+	Type    TrackType
+	CodecID string
+}
+
+// Document is the information read from a Matroska / WebM file.
+type Document struct {
+	// DocType is "matroska" or "webm".
+	DocType  string
+	Duration time.Duration
+	Tracks   []Track
+}
+
+type elementHeaderData struct {
+	id          uint32
+	dataSize    int64
+	unknownSize bool
+}
+
+type decoder struct {
+	midec.ReadAdvancer
+}
+
+func (d *decoder) readOneByte() (byte, error) {
+	buf, err := d.Next(1)
+	if err != nil {
+		return 0, err
+	}
+	return buf[0], nil
+}
+
+// readVint reads a variable size integer. The length marker is kept when keepMarker is true.
+// allOnes reports whether all the value bits were 1.
+func (d *decoder) readVint(maxLength int, keepMarker bool) (v uint64, allOnes bool, err error) {
+	first, err := d.readOneByte()
+	if err != nil {
+		return
+	}
+
+	length := 1
+	for mask := byte(0x80); length <= 8 && first&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > maxLength {
+		err = ErrInvalidElement
+		return
+	}
+
+	v = uint64(first)
+	if !keepMarker {
+		v &= 0xff >> length
+	}
+	allOnes = v == 0xff>>length
+
+	for i := 1; i < length; i++ {
+		b, err := d.readOneByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, false, err
+		}
+		v = v<<8 | uint64(b)
+		allOnes = allOnes && b == 0xff
+	}
+	return
+}
+
+func (d *decoder) decodeElementHeader() (ehd elementHeaderData, err error) {
+	id, _, err := d.readVint(4, true)
+	if err != nil {
+		return
+	}
+
+	size, unknownSize, err := d.readVint(8, false)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	if size > math.MaxInt64 {
+		err = ErrInvalidElement
+		return
+	}
+
+	return elementHeaderData{
+		id:          uint32(id),
+		dataSize:    int64(size),
+		unknownSize: unknownSize,
+	}, nil
+}
+
+func (d *decoder) skipElement(ehd elementHeaderData) error {
+	if ehd.unknownSize {
+		return ErrInvalidElement
+	}
+	return d.Advance(uint(ehd.dataSize))
+}
+
+func (d *decoder) readUint(ehd elementHeaderData) (uint64, error) {
+	if ehd.dataSize > 8 {
+		return 0, ErrInvalidElement
+	}
+
+	buf, err := d.Next(int(ehd.dataSize))
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, b := range buf {
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+func (d *decoder) readFloat(ehd elementHeaderData) (float64, error) {
+	switch ehd.dataSize {
+	case 0:
+		return 0, nil
+	case 4:
+		var f float32
+		err := d.ReadBinary(binary.BigEndian, &f)
+		return float64(f), err
+	case 8:
+		var f float64
+		err := d.ReadBinary(binary.BigEndian, &f)
+		return f, err
+	}
+	return 0, ErrInvalidElement
+}
+
+func (d *decoder) readString(ehd elementHeaderData) (string, error) {
+	if ehd.dataSize > maxStringSize {
+		return "", ErrInvalidElement
+	}
+
+	buf := make([]byte, ehd.dataSize)
+	if _, err := d.ReadFull(buf); err != nil {
+		return "", err
+	}
+	// strings may be padded with zeros
+	for i, b := range buf {
+		if b == 0 {
+			return string(buf[:i]), nil
+		}
+	}
+	return string(buf), nil
+}
+
+// walkChildren calls f for each child element in the parent element of dataSize.
+// Children not consumed by f must be skipped by f.
+func (d *decoder) walkChildren(dataSize int64, f func(elementHeaderData) error) error {
+	end := d.Offset() + dataSize
+	for d.Offset() < end {
+		ehd, err := d.decodeElementHeader()
+		if err != nil {
+			return err
+		}
+		if ehd.unknownSize {
+			return ErrInvalidElement
+		}
+		if err := f(ehd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) decodeEBMLHeader() (string, error) {
+	ehd, err := d.decodeElementHeader()
+	if err != nil {
+		return "", err
+	}
+	if ehd.id != idEBML {
+		return "", ErrInvalidElement
+	}
+
+	docType := ""
+	err = d.walkChildren(ehd.dataSize, func(ehd elementHeaderData) error {
+		if ehd.id != idDocType {
+			return d.skipElement(ehd)
+		}
+
+		var err error
+		docType, err = d.readString(ehd)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if docType != "matroska" && docType != "webm" {
+		return "", ErrUnknownDocType
+	}
+	return docType, nil
+}
+
+func (d *decoder) decodeInfo(dataSize int64) (time.Duration, error) {
+	timestampScale := uint64(defaultTimestampScale)
+	duration := 0.0
+	err := d.walkChildren(dataSize, func(ehd elementHeaderData) error {
+		var err error
+		switch ehd.id {
+		case idTimestampScale:
+			timestampScale, err = d.readUint(ehd)
+		case idDuration:
+			duration, err = d.readFloat(ehd)
+		default:
+			err = d.skipElement(ehd)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(duration * float64(timestampScale)), nil
+}
+
+func (d *decoder) decodeTrackEntry(dataSize int64) (Track, error) {
+	var track Track
+	err := d.walkChildren(dataSize, func(ehd elementHeaderData) error {
+		var err error
+		switch ehd.id {
+		case idTrackNumber:
+			track.Number, err = d.readUint(ehd)
+		case idTrackType:
+			var v uint64
+			v, err = d.readUint(ehd)
+			track.Type = TrackType(v)
+		case idCodecID:
+			track.CodecID, err = d.readString(ehd)
+		default:
+			err = d.skipElement(ehd)
+		}
+		return err
+	})
+	return track, err
+}
+
+func (d *decoder) decodeTracks(dataSize int64) ([]Track, error) {
+	var tracks []Track
+	err := d.walkChildren(dataSize, func(ehd elementHeaderData) error {
+		if ehd.id != idTrackEntry {
+			return d.skipElement(ehd)
+		}
+
+		track, err := d.decodeTrackEntry(ehd.dataSize)
+		if err != nil {
+			return err
+		}
+		tracks = append(tracks, track)
+		return nil
+	})
+	return tracks, err
+}
+
+// decodeDocument reads the EBML header and the elements in Segment until the first Cluster.
+func (d *decoder) decodeDocument() (*Document, error) {
+	docType, err := d.decodeEBMLHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	segment, err := d.decodeElementHeader()
+	if err != nil {
+		return nil, err
+	}
+	if segment.id != idSegment {
+		return nil, ErrInvalidElement
+	}
+
+	doc := &Document{DocType: docType}
+	segmentEnd := d.Offset() + segment.dataSize
+	for segment.unknownSize || d.Offset() < segmentEnd {
+		ehd, err := d.decodeElementHeader()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		switch ehd.id {
+		case idInfo:
+			if doc.Duration, err = d.decodeInfo(ehd.dataSize); err != nil {
+				return nil, err
+			}
+		case idTracks:
+			if doc.Tracks, err = d.decodeTracks(ehd.dataSize); err != nil {
+				return nil, err
+			}
+		case idCluster:
+			// Tracks must come before the first Cluster.
+			return doc, nil
+		default:
+			if err := d.skipElement(ehd); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return doc, nil
+}
+
+func (d *decoder) inspect() (*midec.Info, error) {
+	doc, err := d.decodeDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	videoTracks := 0
+	hasAudio := false
+	for _, t := range doc.Tracks {
+		switch t.Type {
+		case TrackTypeVideo:
+			videoTracks++
+		case TrackTypeAudio:
+			hasAudio = true
+		}
+	}
+
+	info := &midec.Info{
+		Kind:     midec.KindVideo,
+		Duration: doc.Duration,
+		Tracks:   len(doc.Tracks),
+		HasAudio: hasAudio,
+	}
+	// a silent clip with a single video track is the same as an animated image
+	if videoTracks == 1 && len(doc.Tracks) == 1 {
+		info.Kind = midec.KindAnimated
+	}
+	return info, nil
+}
+
+// DecodeDocument reads the DocType, the duration and the tracks.
+func DecodeDocument(r io.Reader) (*Document, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.decodeDocument()
+}
+
+func isAnimated(r io.Reader) (bool, error) {
+	info, err := inspect(r)
+	if err != nil {
+		return false, err
+	}
+	return info.Kind.IsMultiImage(), nil
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.inspect()
+}
+
+func init() {
+	midec.RegisterInspectableFormat("matroska", ebmlHeader, isAnimated, inspect)
+}
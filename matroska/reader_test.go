@@ -0,0 +1,82 @@
+package matroska
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sapphi-red/midec"
+)
+
+const testdataFolder = "../testdata/matroska/"
+
+func Test_inspect(t *testing.T) {
+	t.Parallel()
+
+	runInspect := func(filename string) (*midec.Info, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return inspect(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expected         *midec.Info
+		expectedHasError bool
+	}{
+		{"video.webm", &midec.Info{Kind: midec.KindVideo, Duration: 2500 * time.Millisecond, Tracks: 2, HasAudio: true}, false},
+		{"video.mkv", &midec.Info{Kind: midec.KindVideo, Duration: 3 * time.Second, Tracks: 3, HasAudio: true}, false},
+		{"animated.webm", &midec.Info{Kind: midec.KindAnimated, Duration: time.Second, Tracks: 1}, false},
+		{"invalid-doctype.mkv", nil, true},
+		{"invalid-element.webm", nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runInspect(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Info = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_DecodeDocument(t *testing.T) {
+	t.Parallel()
+
+	fp, err := os.Open(testdataFolder + "video.mkv")
+	if err != nil {
+		panic(err)
+	}
+
+	actual, err := DecodeDocument(fp)
+	if err != nil {
+		t.Fatalf("Error = %v; want HasError = false", err)
+	}
+
+	expected := &Document{
+		DocType:  "matroska",
+		Duration: 3 * time.Second,
+		Tracks: []Track{
+			{Number: 1, Type: TrackTypeVideo, CodecID: "V_MPEG4/ISO/AVC"},
+			{Number: 2, Type: TrackTypeAudio, CodecID: "A_AAC"},
+			{Number: 3, Type: TrackTypeSubtitle, CodecID: "S_TEXT/UTF8"},
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Document = %+v; want %+v", actual, expected)
+	}
+}
@@ -0,0 +1,215 @@
+// Package mng implements a MNG / JNG detector
+package mng
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/sapphi-red/midec"
+	"github.com/sapphi-red/midec/internal/pngchunk"
+)
+
+const (
+	mngHeader = "\x8aMNG\r\n\x1a\n"
+	jngHeader = "\x8bJNG\r\n\x1a\n"
+)
+
+// ErrInvalidHeader indicates that the signature or the first chunk was broken.
+var ErrInvalidHeader = errors.New("midec: (mng) invalid header chunk")
+
+const (
+	mhdrLength = 28
+	jhdrLength = 16
+)
+
+// Movie is the information of a MNG or JNG file.
+type Movie struct {
+	// IsJNG reports whether the file is a JNG, which is always a single image.
+	IsJNG  bool
+	Width  int
+	Height int
+	// The fields below are from MHDR chunk and are 0 for JNG.
+	TicksPerSecond    int
+	NominalLayerCount int
+	NominalFrameCount int
+	NominalPlayTime   int
+	SimplicityProfile uint32
+	// Subimages is the number of IHDR and JHDR chunks.
+	Subimages int
+}
+
+type movieHeaderData struct {
+	FrameWidth        uint32
+	FrameHeight       uint32
+	TicksPerSecond    uint32
+	NominalLayerCount uint32
+	NominalFrameCount uint32
+	NominalPlayTime   uint32
+	SimplicityProfile uint32
+}
+
+type decoder struct {
+	pngchunk.Decoder
+}
+
+func (d *decoder) decodeMHDRChunk(chd pngchunk.ChunkHeaderData) (mhd movieHeaderData, err error) {
+	if chd.TypeID != "MHDR" || chd.Length != mhdrLength {
+		err = ErrInvalidHeader
+		return
+	}
+
+	if err = binary.Read(d, binary.BigEndian, &mhd); err != nil {
+		return
+	}
+
+	err = d.Advance(4) // CRC
+	return
+}
+
+// countSubimages walks chunks until MEND and counts IHDR and JHDR chunks.
+func (d *decoder) countSubimages() (int, error) {
+	count := 0
+	for {
+		chd, err := d.DecodeChunkHeader()
+		if err != nil {
+			return count, err
+		}
+
+		switch chd.TypeID {
+		case "IHDR", "JHDR":
+			count++
+		case "MEND":
+			return count, nil
+		}
+
+		if err := d.SkipChunk(chd); err != nil {
+			return count, err
+		}
+	}
+}
+
+func (d *decoder) decodeMHDR() (*Movie, error) {
+	chd, err := d.DecodeChunkHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	mhd, err := d.decodeMHDRChunk(chd)
+	if err != nil {
+		return nil, err
+	}
+
+	subimages, err := d.countSubimages()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Movie{
+		Width:             int(mhd.FrameWidth),
+		Height:            int(mhd.FrameHeight),
+		TicksPerSecond:    int(mhd.TicksPerSecond),
+		NominalLayerCount: int(mhd.NominalLayerCount),
+		NominalFrameCount: int(mhd.NominalFrameCount),
+		NominalPlayTime:   int(mhd.NominalPlayTime),
+		SimplicityProfile: mhd.SimplicityProfile,
+		Subimages:         subimages,
+	}, nil
+}
+
+func (d *decoder) decodeJHDR() (*Movie, error) {
+	chd, err := d.DecodeChunkHeader()
+	if err != nil {
+		return nil, err
+	}
+	if chd.TypeID != "JHDR" || chd.Length != jhdrLength {
+		return nil, ErrInvalidHeader
+	}
+
+	width, err := d.ReadUint32()
+	if err != nil {
+		return nil, err
+	}
+	height, err := d.ReadUint32()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Movie{
+		IsJNG:     true,
+		Width:     int(width),
+		Height:    int(height),
+		Subimages: 1,
+	}, nil
+}
+
+func (d *decoder) decodeMovie() (*Movie, error) {
+	sigBuf, err := d.Next(len(mngHeader))
+	if err != nil {
+		return nil, err
+	}
+
+	switch string(sigBuf) {
+	case mngHeader:
+		return d.decodeMHDR()
+	case jngHeader:
+		return d.decodeJHDR()
+	}
+	return nil, ErrInvalidHeader
+}
+
+func (d *decoder) inspect() (*midec.Info, error) {
+	m, err := d.decodeMovie()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &midec.Info{Kind: midec.KindStatic, Frames: m.Subimages, Width: m.Width, Height: m.Height}
+	if m.Subimages < 2 {
+		return info, nil
+	}
+
+	info.Kind = midec.KindAnimated
+	// a subimage may be shown as several frames, or several subimages may be layers of a frame
+	if m.NominalFrameCount > 0 {
+		info.Frames = m.NominalFrameCount
+	}
+	info.TicksPerSecond = float64(m.TicksPerSecond)
+	if m.TicksPerSecond > 0 {
+		info.Duration = time.Duration(m.NominalPlayTime) * time.Second / time.Duration(m.TicksPerSecond)
+	}
+	return info, nil
+}
+
+// DecodeMovie reads MHDR chunk and counts subimages of a MNG, or reads JHDR chunk of a JNG.
+func DecodeMovie(r io.Reader) (*Movie, error) {
+	d := decoder{*pngchunk.NewDecoder(r)}
+	return d.decodeMovie()
+}
+
+// isAnimated walks until MEND as inspect does, instead of stopping at the second subimage.
+func isAnimated(r io.Reader) (bool, error) {
+	info, err := inspect(r)
+	if err != nil {
+		return false, err
+	}
+	return info.Kind.IsMultiImage(), nil
+}
+
+func isAnimatedJNG(r io.Reader) (bool, error) {
+	// JNG cannot contain multiple images.
+	d := decoder{*pngchunk.NewDecoder(r)}
+	_, err := d.decodeMovie()
+	return false, err
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{*pngchunk.NewDecoder(r)}
+	return d.inspect()
+}
+
+func init() {
+	midec.RegisterInspectableFormat("mng", mngHeader, isAnimated, inspect)
+	midec.RegisterInspectableFormat("jng", jngHeader, isAnimatedJNG, inspect)
+}
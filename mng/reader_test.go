@@ -0,0 +1,158 @@
+package mng
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sapphi-red/midec"
+)
+
+const testdataFolder = "../testdata/mng/"
+
+func Test_isAnimated(t *testing.T) {
+	t.Parallel()
+
+	runIsAnimated := func(filename string, isAnimated func(io.Reader) (bool, error)) (bool, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return isAnimated(fp)
+	}
+
+	testcases := []struct {
+		filename           string
+		isAnimated         func(io.Reader) (bool, error)
+		expectedIsAnimated bool
+		expectedHasError   bool
+	}{
+		{"animated.mng", isAnimated, true, false},
+		{"static.mng", isAnimated, false, false},
+		{"invalid-header.mng", isAnimated, false, true},
+		{"invalid-mhdr.mng", isAnimated, false, true},
+		{"invalid-chunk.mng", isAnimated, false, true},
+		{"invalid-nomend.mng", isAnimated, false, true},
+		{"static.jng", isAnimatedJNG, false, false},
+		{"invalid-header.jng", isAnimatedJNG, false, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actualIsAnimated, actualErr := runIsAnimated(tc.filename, tc.isAnimated)
+			if tc.expectedIsAnimated != actualIsAnimated {
+				t.Errorf("IsAnimated = %t; want %t", actualIsAnimated, tc.expectedIsAnimated)
+			}
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+		})
+	}
+}
+
+func Test_DecodeMovie(t *testing.T) {
+	t.Parallel()
+
+	runDecodeMovie := func(filename string) (*Movie, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return DecodeMovie(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expected         *Movie
+		expectedHasError bool
+	}{
+		{"animated.mng", &Movie{
+			Width:             16,
+			Height:            16,
+			TicksPerSecond:    10,
+			NominalLayerCount: 3,
+			NominalFrameCount: 3,
+			NominalPlayTime:   30,
+			SimplicityProfile: 0x41,
+			Subimages:         3,
+		}, false},
+		{"static.mng", &Movie{
+			Width:             16,
+			Height:            16,
+			TicksPerSecond:    1,
+			NominalLayerCount: 1,
+			NominalFrameCount: 1,
+			NominalPlayTime:   1,
+			SimplicityProfile: 1,
+			Subimages:         1,
+		}, false},
+		{"static.jng", &Movie{IsJNG: true, Width: 32, Height: 24, Subimages: 1}, false},
+		{"invalid-header.mng", nil, true},
+		{"invalid-nomend.mng", nil, true},
+		{"invalid-header.jng", nil, true},
+		{"../png/static.png", nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runDecodeMovie(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Movie = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_inspect(t *testing.T) {
+	t.Parallel()
+
+	runInspect := func(filename string) (*midec.Info, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return inspect(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expected         *midec.Info
+		expectedHasError bool
+	}{
+		{"animated.mng", &midec.Info{Kind: midec.KindAnimated, Frames: 3, Width: 16, Height: 16, Duration: 3 * time.Second, TicksPerSecond: 10}, false},
+		{"animated-nominal.mng", &midec.Info{Kind: midec.KindAnimated, Frames: 6, Width: 16, Height: 16, Duration: 3 * time.Second, TicksPerSecond: 10}, false},
+		{"static.mng", &midec.Info{Kind: midec.KindStatic, Frames: 1, Width: 16, Height: 16}, false},
+		{"static.jng", &midec.Info{Kind: midec.KindStatic, Frames: 1, Width: 32, Height: 24}, false},
+		{"invalid-chunk.mng", nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runInspect(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Info = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
@@ -3,51 +3,31 @@ package png
 
 import (
 	"encoding/binary"
+	"errors"
 	"io"
+	"time"
 
 	"github.com/sapphi-red/midec"
+	"github.com/sapphi-red/midec/internal/pngchunk"
 )
 
 const pngHeader = "\x89PNG\r\n\x1a\n"
 
-type chunkHeaderData struct {
-	length uint32
-	typeId string
-}
-
-type decoder struct {
-	midec.ReadAdvancer
-}
-
-func (d *decoder) readUint32() (u uint32, err error) {
-	err = binary.Read(d.Reader, binary.BigEndian, &u)
-	return
-}
+// ErrInvalidChunk indicates that IHDR, acTL or fcTL chunk was broken.
+var ErrInvalidChunk = errors.New("midec: (png) invalid chunk")
 
-func (d *decoder) skipHeader() error {
-	return d.Advance(8)
-}
-
-func (d *decoder) decodeChunkHeader() (chd chunkHeaderData, err error) {
-	length, err := d.readUint32()
-	if err != nil {
-		return
-	}
-
-	typeIdBuf := make([]byte, 4)
-	_, err = d.ReadFull(typeIdBuf)
-	if err != nil {
-		return
-	}
+const (
+	ihdrSize = 13
+	actlSize = 8
+	fctlSize = 26
+)
 
-	return chunkHeaderData{
-		length: length,
-		typeId: string(typeIdBuf),
-	}, nil
+type decoder struct {
+	pngchunk.Decoder
 }
 
 func (d *decoder) decodeacTLChunk() (bool, error) {
-	length, err := d.readUint32()
+	length, err := d.ReadUint32()
 	if err != nil {
 		return false, err
 	}
@@ -55,25 +35,18 @@ func (d *decoder) decodeacTLChunk() (bool, error) {
 	return length >= 2, nil
 }
 
-func (d *decoder) skipUnknownChunk(length uint32) error {
-	return d.Advance(
-		uint(length) + // data
-			4, // CRC
-	)
-}
-
 func (d *decoder) decode() (bool, error) {
-	if err := d.skipHeader(); err != nil {
+	if err := d.SkipSignature(); err != nil {
 		return false, err
 	}
 
 	for {
-		chd, err := d.decodeChunkHeader()
+		chd, err := d.DecodeChunkHeader()
 		if err != nil {
 			return false, err
 		}
 
-		switch chd.typeId {
+		switch chd.TypeID {
 		case "acTL":
 			return d.decodeacTLChunk()
 		case "IDAT":
@@ -81,18 +54,105 @@ func (d *decoder) decode() (bool, error) {
 			// so if IDAT comes before acTL, it is not a apng.
 			return false, nil
 		default:
-			if err := d.skipUnknownChunk(chd.length); err != nil {
+			if err := d.SkipChunk(chd); err != nil {
 				return false, err
 			}
 		}
 	}
 }
 
+// readChunkData reads the first size bytes of the chunk data and skips the rest.
+func (d *decoder) readChunkData(chd pngchunk.ChunkHeaderData, size uint32) ([]byte, error) {
+	if chd.Length < size {
+		return nil, ErrInvalidChunk
+	}
+
+	buf := make([]byte, size)
+	if _, err := d.ReadFull(buf); err != nil {
+		return nil, err
+	}
+	return buf, d.SkipChunk(pngchunk.ChunkHeaderData{Length: chd.Length - size})
+}
+
+// fcTLDelay returns the delay in fcTL chunk data.
+func fcTLDelay(data []byte) time.Duration {
+	num := binary.BigEndian.Uint16(data[20:22])
+	den := binary.BigEndian.Uint16(data[22:24])
+	// 0 denominator means 1/100 sec
+	if den == 0 {
+		den = 100
+	}
+	return time.Duration(num) * time.Second / time.Duration(den)
+}
+
+func (d *decoder) inspect() (*midec.Info, error) {
+	if err := d.SkipSignature(); err != nil {
+		return nil, err
+	}
+
+	info := &midec.Info{Kind: midec.KindStatic, Frames: 1}
+	for {
+		chd, err := d.DecodeChunkHeader()
+		if err != nil {
+			return nil, err
+		}
+
+		switch chd.TypeID {
+		case "IHDR":
+			data, err := d.readChunkData(chd, ihdrSize)
+			if err != nil {
+				return nil, err
+			}
+			info.Width = int(binary.BigEndian.Uint32(data[0:4]))
+			info.Height = int(binary.BigEndian.Uint32(data[4:8]))
+		case "acTL":
+			data, err := d.readChunkData(chd, actlSize)
+			if err != nil {
+				return nil, err
+			}
+			info.Frames = int(binary.BigEndian.Uint32(data[0:4]))
+			info.Loops = int(binary.BigEndian.Uint32(data[4:8]))
+			if info.Loops == 0 {
+				info.Loops = midec.LoopInfinite
+			}
+			if info.Frames >= 2 {
+				info.Kind = midec.KindAnimated
+			}
+		case "fcTL":
+			data, err := d.readChunkData(chd, fctlSize)
+			if err != nil {
+				return nil, err
+			}
+			info.Duration += fcTLDelay(data)
+		case "IDAT":
+			// without acTL before IDAT, it is a static PNG
+			if info.Kind == midec.KindStatic {
+				info.Loops = 0
+				return info, nil
+			}
+			if err := d.SkipChunk(chd); err != nil {
+				return nil, err
+			}
+		case "IEND":
+			return info, nil
+		default:
+			if err := d.SkipChunk(chd); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
 func isAnimated(r io.Reader) (bool, error) {
-	d := decoder{*midec.NewReadAdvancer(r)}
+	d := decoder{*pngchunk.NewDecoder(r)}
 	return d.decode()
 }
 
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{*pngchunk.NewDecoder(r)}
+	return d.inspect()
+}
+
 func init() {
-	midec.RegisterFormat("png", pngHeader, isAnimated)
+	midec.RegisterInspectableFormat("png", pngHeader, isAnimated, inspect)
 }
@@ -2,7 +2,11 @@ package png
 
 import (
 	"os"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/sapphi-red/midec"
 )
 
 const testdataFolder = "../testdata/png/"
@@ -47,3 +51,45 @@ func Test_isAnimated(t *testing.T) {
 		})
 	}
 }
+
+func Test_inspect(t *testing.T) {
+	t.Parallel()
+
+	runInspect := func(filename string) (*midec.Info, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return inspect(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expected         *midec.Info
+		expectedHasError bool
+	}{
+		{"animated.png", &midec.Info{Kind: midec.KindAnimated, Frames: 30, Loops: midec.LoopInfinite, Width: 242, Height: 175, Duration: 2533 * time.Millisecond}, false},
+		{"static.png", &midec.Info{Kind: midec.KindStatic, Frames: 1, Width: 242, Height: 175}, false},
+		{"invalid-actl-chunk.png", nil, true},
+		{"invalid-unknown-chunk.png", nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runInspect(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Info = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
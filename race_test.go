@@ -0,0 +1,8 @@
+//go:build race
+// +build race
+
+package midec_test
+
+func init() {
+	raceEnabled = true
+}
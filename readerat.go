@@ -0,0 +1,68 @@
+package midec
+
+import (
+	"io"
+)
+
+// seekerReaderAt implements io.ReaderAt with an io.ReadSeeker. It is not safe for concurrent use.
+type seekerReaderAt struct {
+	rs io.ReadSeeker
+}
+
+func (s seekerReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := s.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(s.rs, p)
+}
+
+// AsReaderAt returns an io.ReaderAt which reads the rest of r, and the size of it.
+// It is useful for formats that need random access, such as zip archives.
+// The position of r is undefined after reading with the returned io.ReaderAt.
+// ErrNotSeekable is returned when r does not implement io.Seeker.
+func AsReaderAt(r io.Reader) (io.ReaderAt, int64, error) {
+	var rs io.ReadSeeker
+	var start int64
+	switch rr := r.(type) {
+	case *traceReader:
+		return AsReaderAt(rr.reader)
+	case *seekReader:
+		rs = rr.rs
+		cur, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, err
+		}
+		start = cur - int64(rr.Buffered())
+	case io.ReadSeeker:
+		rs = rr
+		cur, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, err
+		}
+		start = cur
+	default:
+		return nil, 0, ErrNotSeekable
+	}
+
+	end, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var ra io.ReaderAt = seekerReaderAt{rs}
+	if rra, ok := rs.(io.ReaderAt); ok {
+		ra = rra
+	}
+	return io.NewSectionReader(ra, start, end-start), end - start, nil
+}
+
+// IsAnimatedReaderAt is the same as IsAnimated but reads from an io.ReaderAt of size.
+// Formats that need random access, such as zip archives, can be read without io.Seeker.
+func IsAnimatedReaderAt(r io.ReaderAt, size int64) (bool, error) {
+	return IsAnimated(io.NewSectionReader(r, 0, size))
+}
+
+// InspectReaderAt is the same as Inspect but reads from an io.ReaderAt of size.
+func InspectReaderAt(r io.ReaderAt, size int64) (*Info, error) {
+	return Inspect(io.NewSectionReader(r, 0, size))
+}
@@ -0,0 +1,61 @@
+package midec_test
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/sapphi-red/midec"
+)
+
+func Test_AsReaderAt(t *testing.T) {
+	t.Parallel()
+
+	r := bytes.NewReader([]byte("0123456789"))
+	if _, err := r.Read(make([]byte, 2)); err != nil {
+		panic(err)
+	}
+
+	ra, size, err := midec.AsReaderAt(r)
+	if err != nil {
+		t.Fatalf("Error = %v; want HasError = false", err)
+	}
+	if size != 8 {
+		t.Errorf("Size = %d; want 8", size)
+	}
+
+	buf := make([]byte, 3)
+	if _, err := ra.ReadAt(buf, 1); err != nil {
+		t.Fatalf("Error = %v; want HasError = false", err)
+	}
+	if string(buf) != "345" {
+		t.Errorf("ReadAt = %s; want 345", buf)
+	}
+}
+
+func Test_AsReaderAt_NotSeekable(t *testing.T) {
+	t.Parallel()
+
+	r := bufio.NewReader(bytes.NewReader([]byte("0123456789")))
+	if _, _, err := midec.AsReaderAt(r); err != midec.ErrNotSeekable {
+		t.Errorf("Error = %v; want %v", err, midec.ErrNotSeekable)
+	}
+}
+
+func Test_InspectReaderAt(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile(testdataFolder + "gif/animated.gif")
+	if err != nil {
+		panic(err)
+	}
+
+	info, err := midec.InspectReaderAt(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("Error = %v; want HasError = false", err)
+	}
+	if info.Kind != midec.KindAnimated {
+		t.Errorf("Kind = %v; want %v", info.Kind, midec.KindAnimated)
+	}
+}
@@ -0,0 +1,347 @@
+// Package remote reads an object over HTTP with Range requests, so that it can be inspected
+// without downloading the whole object.
+//
+//	r, err := remote.Open(ctx, "https://example.com/image.gif", remote.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	animated, err := midec.IsAnimated(r)
+//
+// The object is fetched in blocks, and the recently fetched blocks are cached.
+// Since Reader implements io.Seeker, the decoders skip over the data they do not need, such as
+// the image data of an ISOBMFF mdat box, without fetching it.
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultBlockSize is the size of a block used when Options.BlockSize is 0.
+	DefaultBlockSize = 4 << 10
+	// DefaultCacheBlocks is the number of cached blocks used when Options.CacheBlocks is 0.
+	DefaultCacheBlocks = 16
+)
+
+var (
+	// ErrRangeNotSupported indicates that the server responded the whole object to a Range request.
+	ErrRangeNotSupported = errors.New("midec: (remote) server does not support range requests")
+	// ErrUnexpectedStatus indicates that the server responded with a status other than 206 Partial Content.
+	ErrUnexpectedStatus = errors.New("midec: (remote) unexpected status")
+	// ErrInvalidContentRange indicates that the Content-Range header is missing or does not match the request.
+	ErrInvalidContentRange = errors.New("midec: (remote) invalid Content-Range")
+)
+
+// Options configures Open.
+type Options struct {
+	// Client is used to send the requests. It is http.DefaultClient when nil.
+	Client *http.Client
+	// Header is added to each request, for example to authorize it.
+	Header http.Header
+	// BlockSize is the size in bytes of the ranges requested. It is DefaultBlockSize when 0.
+	BlockSize int
+	// CacheBlocks is the maximum number of blocks kept in memory. It is DefaultCacheBlocks when 0.
+	CacheBlocks int
+}
+
+// Reader reads an object with Range requests. It implements io.ReaderAt, io.Reader and io.Seeker.
+// ReadAt is safe for concurrent use, but Read and Seek are not.
+type Reader struct {
+	ctx         context.Context
+	url         string
+	client      *http.Client
+	header      http.Header
+	blockSize   int64
+	cacheBlocks int
+	size        int64
+
+	mu     sync.Mutex
+	blocks map[int64][]byte
+	// order is the indexes of the cached blocks in the order they were fetched.
+	order    []int64
+	fetched  int64
+	requests int
+
+	// offset is the position of Read and Seek.
+	offset int64
+}
+
+// Open fetches the first block of the object at url and returns a Reader for it.
+// ctx is used for all requests sent by the Reader.
+func Open(ctx context.Context, url string, opts Options) (*Reader, error) {
+	r := &Reader{
+		ctx:         ctx,
+		url:         url,
+		client:      opts.Client,
+		header:      opts.Header,
+		blockSize:   int64(opts.BlockSize),
+		cacheBlocks: opts.CacheBlocks,
+		blocks:      make(map[int64][]byte),
+	}
+	if r.client == nil {
+		r.client = http.DefaultClient
+	}
+	if r.blockSize <= 0 {
+		r.blockSize = DefaultBlockSize
+	}
+	if r.cacheBlocks <= 0 {
+		r.cacheBlocks = DefaultCacheBlocks
+	}
+
+	// the size is not known yet, so the first block is fetched without fetchBlocks
+	resp, err := r.get(0, r.blockSize-1)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// an empty object cannot satisfy any range, so some servers respond 200 without Content-Range
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if resp.ContentLength == 0 {
+			return r, nil
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		if _, _, size, err := parseContentRange(resp.Header.Get("Content-Range")); err == nil && size == 0 {
+			return r, nil
+		}
+	}
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	start, end, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return nil, err
+	}
+	if start != 0 || size < 0 {
+		return nil, ErrInvalidContentRange
+	}
+	r.size = size
+
+	buf := make([]byte, end-start+1)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		return nil, err
+	}
+	r.fetched += int64(len(buf))
+	r.storeBlocks(0, buf)
+	return r, nil
+}
+
+// Size returns the size of the object.
+func (r *Reader) Size() int64 {
+	return r.size
+}
+
+// Fetched returns the number of bytes fetched and the number of requests sent so far.
+func (r *Reader) Fetched() (bytes int64, requests int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fetched, r.requests
+}
+
+func (r *Reader) get(start, end int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	// the ranges would not match with a compressed body
+	req.Header.Set("Accept-Encoding", "identity")
+
+	r.requests++
+	return r.client.Do(req)
+}
+
+func checkStatus(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return nil
+	case http.StatusOK:
+		return ErrRangeNotSupported
+	}
+	return fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+}
+
+// parseContentRange parses "bytes start-end/size". size is -1 when it is "*".
+// For an unsatisfied range, "bytes */size", start and end are -1.
+func parseContentRange(s string) (start, end, size int64, err error) {
+	s = strings.TrimPrefix(s, "bytes ")
+	slash := strings.IndexByte(s, '/')
+	if slash < 0 {
+		return 0, 0, 0, ErrInvalidContentRange
+	}
+	rng, total := s[:slash], s[slash+1:]
+
+	size = -1
+	if total != "*" {
+		if size, err = strconv.ParseInt(total, 10, 64); err != nil || size < 0 {
+			return 0, 0, 0, ErrInvalidContentRange
+		}
+	}
+
+	if rng == "*" {
+		return -1, -1, size, nil
+	}
+	dash := strings.IndexByte(rng, '-')
+	if dash < 0 {
+		return 0, 0, 0, ErrInvalidContentRange
+	}
+	start, err1 := strconv.ParseInt(rng[:dash], 10, 64)
+	end, err2 := strconv.ParseInt(rng[dash+1:], 10, 64)
+	if err1 != nil || err2 != nil || start < 0 || end < start || (size >= 0 && end >= size) {
+		return 0, 0, 0, ErrInvalidContentRange
+	}
+	return start, end, size, nil
+}
+
+// storeBlocks splits buf starting from the block first into blocks and caches them.
+// The oldest blocks are evicted when the cache is full.
+func (r *Reader) storeBlocks(first int64, buf []byte) {
+	for i := first; len(buf) > 0; i++ {
+		n := r.blockSize
+		if int64(len(buf)) < n {
+			n = int64(len(buf))
+		}
+		if _, ok := r.blocks[i]; !ok {
+			r.order = append(r.order, i)
+		}
+		r.blocks[i] = buf[:n:n]
+		buf = buf[n:]
+	}
+
+	for len(r.order) > r.cacheBlocks {
+		delete(r.blocks, r.order[0])
+		r.order = r.order[1:]
+	}
+}
+
+// fetchBlocks fetches the blocks from first to last with a single request.
+func (r *Reader) fetchBlocks(first, last int64) ([]byte, error) {
+	start := first * r.blockSize
+	end := (last+1)*r.blockSize - 1
+	if end >= r.size {
+		end = r.size - 1
+	}
+
+	resp, err := r.get(start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	if s, e, _, err := parseContentRange(resp.Header.Get("Content-Range")); err != nil || s != start || e != end {
+		return nil, ErrInvalidContentRange
+	}
+
+	buf := make([]byte, end-start+1)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		return nil, err
+	}
+	r.fetched += int64(len(buf))
+	r.storeBlocks(first, buf)
+	return buf, nil
+}
+
+// ReadAt implements io.ReaderAt. The consecutive blocks not cached are fetched with a single request.
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("midec: (remote) negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	end := off + int64(len(p))
+	if end > r.size {
+		end = r.size
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the blocks are collected before copying since fetching may evict the cached ones
+	first, last := off/r.blockSize, (end-1)/r.blockSize
+	blocks := make([][]byte, 0, last-first+1)
+	for i := first; i <= last; {
+		if b, ok := r.blocks[i]; ok {
+			blocks = append(blocks, b)
+			i++
+			continue
+		}
+
+		j := i
+		for j < last {
+			if _, ok := r.blocks[j+1]; ok {
+				break
+			}
+			j++
+		}
+		buf, err := r.fetchBlocks(i, j)
+		if err != nil {
+			return 0, err
+		}
+		for ; i <= j; i++ {
+			n := r.blockSize
+			if int64(len(buf)) < n {
+				n = int64(len(buf))
+			}
+			blocks = append(blocks, buf[:n])
+			buf = buf[n:]
+		}
+	}
+
+	n := copy(p[:end-off], blocks[0][off-first*r.blockSize:])
+	for _, b := range blocks[1:] {
+		n += copy(p[n:end-off], b)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Read implements io.Reader.
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	n, err := r.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker. Seeking does not send any request.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("midec: (remote) invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("midec: (remote) negative position")
+	}
+	r.offset = offset
+	return offset, nil
+}
@@ -0,0 +1,229 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sapphi-red/midec"
+	_ "github.com/sapphi-red/midec/gif"
+	_ "github.com/sapphi-red/midec/isobmff"
+)
+
+const testdataFolder = "../testdata/"
+
+func readTestdata(filename string) []byte {
+	b, err := os.ReadFile(testdataFolder + filename)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// newLargeMP4 returns movie.mp4 with its mdat box enlarged to about 1 MB, so that moov follows a large mdat.
+func newLargeMP4() []byte {
+	const mdatOffset, moovOffset = 40, 6444
+	movie := readTestdata("isobmff/movie.mp4")
+
+	mdat := make([]byte, 1<<20)
+	binary.BigEndian.PutUint32(mdat, uint32(len(mdat)))
+	copy(mdat[4:], "mdat")
+
+	var b bytes.Buffer
+	b.Write(movie[:mdatOffset])
+	b.Write(mdat)
+	b.Write(movie[moovOffset:])
+	return b.Bytes()
+}
+
+// newServer serves the objects with the support of Range requests.
+func newServer(objects map[string][]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := objects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader(b))
+	}))
+}
+
+func Test_Reader_ReadAt(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	srv := newServer(map[string][]byte{"/data": data})
+	defer srv.Close()
+
+	r, err := Open(context.Background(), srv.URL+"/data", Options{BlockSize: 16, CacheBlocks: 2})
+	if err != nil {
+		t.Fatalf("Error = %v; want HasError = false", err)
+	}
+	if r.Size() != int64(len(data)) {
+		t.Errorf("Size = %d; want %d", r.Size(), len(data))
+	}
+
+	testcases := []struct {
+		off            int64
+		length         int
+		expectedLength int
+		expectedErr    error
+	}{
+		{0, 10, 10, nil},
+		{10, 10, 10, nil},
+		{30, 50, 50, nil},
+		{3, 90, 90, nil},
+		{90, 20, 10, io.EOF},
+		{100, 1, 0, io.EOF},
+		{99, 1, 1, nil},
+	}
+
+	for _, tc := range testcases {
+		p := make([]byte, tc.length)
+		n, err := r.ReadAt(p, tc.off)
+		if err != tc.expectedErr {
+			t.Errorf("ReadAt(%d, %d): Error = %v; want %v", tc.length, tc.off, err, tc.expectedErr)
+		}
+		if n != tc.expectedLength {
+			t.Errorf("ReadAt(%d, %d): N = %d; want %d", tc.length, tc.off, n, tc.expectedLength)
+		}
+		if expected := data[tc.off : tc.off+int64(n)]; !bytes.Equal(p[:n], expected) {
+			t.Errorf("ReadAt(%d, %d): Data = %v; want %v", tc.length, tc.off, p[:n], expected)
+		}
+	}
+}
+
+func Test_Reader_Cache(t *testing.T) {
+	t.Parallel()
+
+	srv := newServer(map[string][]byte{"/data": make([]byte, 100)})
+	defer srv.Close()
+
+	r, err := Open(context.Background(), srv.URL+"/data", Options{BlockSize: 16, CacheBlocks: 2})
+	if err != nil {
+		t.Fatalf("Error = %v; want HasError = false", err)
+	}
+
+	p := make([]byte, 8)
+	// the first block is fetched by Open, and the others by the first ReadAt of each
+	for _, off := range []int64{0, 4, 20, 8, 50, 52} {
+		if _, err := r.ReadAt(p, off); err != nil {
+			t.Fatalf("Error = %v; want HasError = false", err)
+		}
+	}
+	if fetched, requests := r.Fetched(); fetched != 48 || requests != 3 {
+		t.Errorf("Fetched = %d, %d; want 48, 3", fetched, requests)
+	}
+
+	// the first block was evicted
+	if _, err := r.ReadAt(p, 0); err != nil {
+		t.Fatalf("Error = %v; want HasError = false", err)
+	}
+	if _, requests := r.Fetched(); requests != 4 {
+		t.Errorf("Requests = %d; want 4", requests)
+	}
+}
+
+func Test_Inspect(t *testing.T) {
+	t.Parallel()
+
+	objects := map[string][]byte{
+		"/animated.gif":  readTestdata("gif/animated.gif"),
+		"/animated.avif": readTestdata("isobmff/animated.avif"),
+		"/large.mp4":     newLargeMP4(),
+	}
+	srv := newServer(objects)
+	// the subtests run after returning
+	t.Cleanup(srv.Close)
+
+	testcases := []struct {
+		path               string
+		expectedKind       midec.Kind
+		expectedMaxFetched int64
+	}{
+		// the image data after the second frame is not read
+		{"/animated.gif", midec.KindAnimated, 16 << 10},
+		// moov is at the front
+		{"/animated.avif", midec.KindAnimated, DefaultBlockSize},
+		// mdat is skipped by seeking
+		{"/large.mp4", midec.KindVideo, 3 * DefaultBlockSize},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := Open(context.Background(), srv.URL+tc.path, Options{})
+			if err != nil {
+				t.Fatalf("Error = %v; want HasError = false", err)
+			}
+
+			var actualKind midec.Kind
+			if tc.expectedKind == midec.KindVideo {
+				info, err := midec.Inspect(r)
+				if err != nil {
+					t.Fatalf("Error = %v; want HasError = false", err)
+				}
+				actualKind = info.Kind
+			} else {
+				animated, err := midec.IsAnimated(r)
+				if err != nil {
+					t.Fatalf("Error = %v; want HasError = false", err)
+				}
+				if animated {
+					actualKind = midec.KindAnimated
+				}
+			}
+			if actualKind != tc.expectedKind {
+				t.Errorf("Kind = %v; want %v", actualKind, tc.expectedKind)
+			}
+
+			fetched, _ := r.Fetched()
+			if fetched > tc.expectedMaxFetched {
+				t.Errorf("Fetched = %d of %d; want <= %d", fetched, len(objects[tc.path]), tc.expectedMaxFetched)
+			}
+		})
+	}
+}
+
+func Test_Open(t *testing.T) {
+	t.Parallel()
+
+	srv := newServer(map[string][]byte{"/empty": {}})
+	defer srv.Close()
+	noRange := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(readTestdata("gif/animated.gif"))
+	}))
+	defer noRange.Close()
+
+	testcases := []struct {
+		name        string
+		url         string
+		expectedErr error
+	}{
+		{"empty", srv.URL + "/empty", nil},
+		{"not found", srv.URL + "/notfound", ErrUnexpectedStatus},
+		{"range not supported", noRange.URL, ErrRangeNotSupported},
+	}
+
+	for _, tc := range testcases {
+		r, err := Open(context.Background(), tc.url, Options{})
+		if !errors.Is(err, tc.expectedErr) {
+			t.Errorf("%s: Error = %v; want %v", tc.name, err, tc.expectedErr)
+		}
+		if err == nil && r.Size() != 0 {
+			t.Errorf("%s: Size = %d; want 0", tc.name, r.Size())
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package scan
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Writer writes the results as a streaming report.
+type Writer interface {
+	Write(res Result) error
+	// Flush writes any buffered data. It should be called after the last Write.
+	Flush() error
+}
+
+// record is a row of the report.
+type record struct {
+	Path     string  `json:"path"`
+	Size     int64   `json:"size"`
+	Verdict  Verdict `json:"verdict"`
+	Format   string  `json:"format,omitempty"`
+	Kind     string  `json:"kind,omitempty"`
+	Frames   int     `json:"frames"`
+	Loops    int     `json:"loops"`
+	Width    int     `json:"width"`
+	Height   int     `json:"height"`
+	Duration float64 `json:"duration"` // seconds
+	Error    string  `json:"error,omitempty"`
+}
+
+func newRecord(res Result) record {
+	rec := record{Path: res.Path, Size: res.Size, Verdict: res.Verdict, Format: res.Format}
+	if res.Err != nil {
+		rec.Error = res.Err.Error()
+	}
+	if res.Info != nil {
+		rec.Kind = res.Info.Kind.String()
+		rec.Frames = res.Info.Frames
+		rec.Loops = res.Info.Loops
+		rec.Width = res.Info.Width
+		rec.Height = res.Info.Height
+		rec.Duration = res.Info.Duration.Seconds()
+	}
+	return rec
+}
+
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+// NewJSONLWriter returns a Writer which writes a JSON object per line.
+func NewJSONLWriter(w io.Writer) Writer {
+	return jsonlWriter{json.NewEncoder(w)}
+}
+
+func (w jsonlWriter) Write(res Result) error {
+	return w.enc.Encode(newRecord(res))
+}
+
+func (jsonlWriter) Flush() error {
+	return nil
+}
+
+var csvHeader = []string{"path", "size", "verdict", "format", "kind", "frames", "loops", "width", "height", "duration", "error"}
+
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+// NewCSVWriter returns a Writer which writes CSV with a header row.
+func NewCSVWriter(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) Write(res Result) error {
+	if !w.headerWritten {
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+
+	rec := newRecord(res)
+	return w.w.Write([]string{
+		rec.Path,
+		strconv.FormatInt(rec.Size, 10),
+		string(rec.Verdict),
+		rec.Format,
+		rec.Kind,
+		strconv.Itoa(rec.Frames),
+		strconv.Itoa(rec.Loops),
+		strconv.Itoa(rec.Width),
+		strconv.Itoa(rec.Height),
+		strconv.FormatFloat(rec.Duration, 'f', -1, 64),
+		rec.Error,
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// Summary counts the results per format and verdict.
+type Summary struct {
+	Total int
+	// Verdicts is the number of files per verdict.
+	Verdicts map[Verdict]int
+	// Formats is the number of files per format and verdict, including the corrupt files.
+	// The files in an unknown format and those which could not be read are not counted.
+	Formats map[string]map[Verdict]int
+}
+
+// NewSummary creates Summary.
+func NewSummary() *Summary {
+	return &Summary{
+		Verdicts: make(map[Verdict]int),
+		Formats:  make(map[string]map[Verdict]int),
+	}
+}
+
+// Add counts res.
+func (s *Summary) Add(res Result) {
+	s.Total++
+	s.Verdicts[res.Verdict]++
+	if res.Format == "" {
+		return
+	}
+
+	verdicts, ok := s.Formats[res.Format]
+	if !ok {
+		verdicts = make(map[Verdict]int)
+		s.Formats[res.Format] = verdicts
+	}
+	verdicts[res.Verdict]++
+}
+
+var verdictOrder = []Verdict{VerdictAnimated, VerdictStatic, VerdictUnknown, VerdictCorrupt, VerdictError}
+
+// WriteTo writes the summary as a table sorted by format.
+func (s *Summary) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	tw := tabwriter.NewWriter(cw, 0, 0, 2, ' ', 0)
+
+	formats := make([]string, 0, len(s.Formats))
+	for format := range s.Formats {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	fmt.Fprintln(tw, "FORMAT\tVERDICT\tCOUNT")
+	for _, format := range formats {
+		for _, verdict := range verdictOrder {
+			if count := s.Formats[format][verdict]; count > 0 {
+				fmt.Fprintf(tw, "%s\t%s\t%d\n", format, verdict, count)
+			}
+		}
+	}
+	for _, verdict := range verdictOrder {
+		if count := s.Verdicts[verdict]; count > 0 {
+			fmt.Fprintf(tw, "*\t%s\t%d\n", verdict, count)
+		}
+	}
+	fmt.Fprintf(tw, "*\t*\t%d\n", s.Total)
+
+	err := tw.Flush()
+	return cw.n, err
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,212 @@
+// Package scan walks a file system and inspects the files in parallel
+package scan
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/sapphi-red/midec"
+)
+
+// Verdict is the outcome of inspecting a file.
+type Verdict string
+
+const (
+	// VerdictAnimated is a file whose Kind reported by midec.Inspect is multi-image.
+	VerdictAnimated Verdict = "animated"
+	// VerdictStatic is a file in a registered format which is not multi-image, including a video.
+	VerdictStatic Verdict = "static"
+	// VerdictUnknown is a file in an unregistered format.
+	VerdictUnknown Verdict = "unknown"
+	// VerdictCorrupt is a file which was broken in the middle.
+	VerdictCorrupt Verdict = "corrupt"
+	// VerdictError is a file or a directory which could not be read.
+	VerdictError Verdict = "error"
+)
+
+// Options configures Scan.
+type Options struct {
+	// Workers is the number of files inspected at the same time. It is runtime.NumCPU() when 0.
+	Workers int
+	// Include is the globs of the files to be inspected. All files are inspected when empty.
+	// A glob without '/' is matched against the base name, otherwise against the whole path.
+	Include []string
+	// Exclude is the globs of the files not to be inspected. It is applied after Include.
+	Exclude []string
+	// MaxSize is the maximum size of the files to be inspected. There is no limit when 0.
+	MaxSize int64
+}
+
+// Result is the result of a file.
+type Result struct {
+	// Path is the slash-separated path in the file system.
+	Path string
+	Size int64
+	// Format is the name of the format. It is also set for a corrupt file, and empty for a file
+	// in an unknown format or which could not be read.
+	Format string
+	// Info is nil when Err is not nil.
+	Info    *midec.Info
+	Verdict Verdict
+	Err     error
+}
+
+// Classify returns the verdict of the result of midec.Inspect.
+func Classify(info *midec.Info, err error) Verdict {
+	switch {
+	case errors.Is(err, midec.ErrFormat):
+		return VerdictUnknown
+	case err != nil:
+		return VerdictCorrupt
+	case info.Kind.IsMultiImage():
+		return VerdictAnimated
+	}
+	return VerdictStatic
+}
+
+type job struct {
+	path string
+	size int64
+}
+
+// ValidateGlobs checks that the globs are well-formed, since path.Match only reports it when matching.
+func ValidateGlobs(globs []string) error {
+	for _, glob := range globs {
+		if _, err := path.Match(glob, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matchAny(globs []string, name string) bool {
+	for _, glob := range globs {
+		target := name
+		if !strings.Contains(glob, "/") {
+			target = path.Base(name)
+		}
+		if ok, _ := path.Match(glob, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Options) accepts(name string, size int64) bool {
+	if len(o.Include) > 0 && !matchAny(o.Include, name) {
+		return false
+	}
+	if matchAny(o.Exclude, name) {
+		return false
+	}
+	return o.MaxSize <= 0 || size <= o.MaxSize
+}
+
+func inspect(fsys fs.FS, j job) Result {
+	res := Result{Path: j.path, Size: j.size}
+
+	f, err := fsys.Open(j.path)
+	if err != nil {
+		res.Verdict, res.Err = VerdictError, err
+		return res
+	}
+	defer f.Close()
+
+	info, err := midec.Inspect(f)
+	res.Verdict = Classify(info, err)
+	if err != nil {
+		res.Err = err
+	} else {
+		res.Info = info
+		res.Format = info.Format
+	}
+
+	// the format of a corrupt file is detected again from the beginning
+	if res.Verdict == VerdictCorrupt {
+		if s, ok := f.(io.Seeker); ok {
+			if _, err := s.Seek(0, io.SeekStart); err == nil {
+				res.Format, _ = midec.DetectFormat(f)
+			}
+		}
+	}
+	return res
+}
+
+// walk sends the files to be inspected to jobs, and the errors while walking to results.
+func walk(ctx context.Context, fsys fs.FS, root string, opts *Options, jobs chan<- job, results chan<- Result) {
+	_ = fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			select {
+			case results <- Result{Path: p, Verdict: VerdictError, Err: err}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			// the rest of the directory is skipped by WalkDir
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			// the file was removed after listing
+			return nil
+		}
+		if !opts.accepts(p, fi.Size()) {
+			return nil
+		}
+
+		select {
+		case jobs <- job{path: p, size: fi.Size()}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// Scan walks fsys from root and inspects the regular files with a bounded number of workers.
+// The results are sent in the order of completion, and the channel is closed when all files are done
+// or ctx is canceled.
+func Scan(ctx context.Context, fsys fs.FS, root string, opts Options) <-chan Result {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan job)
+	results := make(chan Result)
+
+	var wg sync.WaitGroup
+	wg.Add(workers + 1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		walk(ctx, fsys, root, &opts, jobs, results)
+	}()
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				select {
+				case results <- inspect(fsys, j):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
@@ -0,0 +1,224 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/sapphi-red/midec/gif"
+	_ "github.com/sapphi-red/midec/png"
+)
+
+const testdataFolder = "../testdata/"
+
+func readTestdata(filename string) []byte {
+	b, err := os.ReadFile(testdataFolder + filename)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func newTestFS() fstest.MapFS {
+	return fstest.MapFS{
+		"a/animated.gif":    {Data: readTestdata("gif/animated.gif")},
+		"a/static.png":      {Data: readTestdata("png/static.png")},
+		"a/b/loop.gif":      {Data: readTestdata("gif/loop.gif")},
+		"a/b/broken.gif":    {Data: readTestdata("gif/invalid-block-unknown.gif")},
+		"a/b/readme.txt":    {Data: []byte("not an image")},
+		"c/static-copy.png": {Data: readTestdata("png/static.png")},
+		"c/empty":           {Data: []byte{}},
+	}
+}
+
+func runScan(root string, opts Options) map[string]Verdict {
+	actual := make(map[string]Verdict)
+	for res := range Scan(context.Background(), newTestFS(), root, opts) {
+		actual[res.Path] = res.Verdict
+	}
+	return actual
+}
+
+func Test_Scan(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		root     string
+		opts     Options
+		expected map[string]Verdict
+	}{
+		{
+			"all",
+			".",
+			Options{Workers: 2},
+			map[string]Verdict{
+				"a/animated.gif":    VerdictAnimated,
+				"a/static.png":      VerdictStatic,
+				"a/b/loop.gif":      VerdictAnimated,
+				"a/b/broken.gif":    VerdictCorrupt,
+				"a/b/readme.txt":    VerdictUnknown,
+				"c/static-copy.png": VerdictStatic,
+				"c/empty":           VerdictUnknown,
+			},
+		},
+		{
+			"root",
+			"a/b",
+			Options{},
+			map[string]Verdict{
+				"a/b/loop.gif":   VerdictAnimated,
+				"a/b/broken.gif": VerdictCorrupt,
+				"a/b/readme.txt": VerdictUnknown,
+			},
+		},
+		{
+			"include base name",
+			".",
+			Options{Include: []string{"*.gif"}},
+			map[string]Verdict{
+				"a/animated.gif": VerdictAnimated,
+				"a/b/loop.gif":   VerdictAnimated,
+				"a/b/broken.gif": VerdictCorrupt,
+			},
+		},
+		{
+			"include path and exclude",
+			".",
+			Options{Include: []string{"a/*"}, Exclude: []string{"*.png"}},
+			map[string]Verdict{
+				"a/animated.gif": VerdictAnimated,
+			},
+		},
+		{
+			"max size",
+			".",
+			Options{MaxSize: 1000},
+			map[string]Verdict{
+				"a/b/broken.gif": VerdictCorrupt,
+				"a/b/readme.txt": VerdictUnknown,
+				"c/empty":        VerdictUnknown,
+			},
+		},
+		{
+			"not found",
+			"d",
+			Options{},
+			map[string]Verdict{
+				"d": VerdictError,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := runScan(tc.root, tc.opts)
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Results = %v; want %v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_Scan_Canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// it should not block even if the results are not received
+	for range Scan(ctx, newTestFS(), ".", Options{Workers: 1}) {
+	}
+}
+
+func Test_Summary(t *testing.T) {
+	t.Parallel()
+
+	summary := NewSummary()
+	for res := range Scan(context.Background(), newTestFS(), ".", Options{}) {
+		summary.Add(res)
+	}
+
+	var buf bytes.Buffer
+	if _, err := summary.WriteTo(&buf); err != nil {
+		t.Fatalf("Error = %v; want HasError = false", err)
+	}
+
+	expected := strings.Join([]string{
+		"FORMAT  VERDICT   COUNT",
+		"gif     animated  2",
+		"gif     corrupt   1",
+		"png     static    2",
+		"*       animated  2",
+		"*       static    2",
+		"*       unknown   2",
+		"*       corrupt   1",
+		"*       *         7",
+		"",
+	}, "\n")
+	if buf.String() != expected {
+		t.Errorf("Summary = %q; want %q", buf.String(), expected)
+	}
+}
+
+func Test_Writer(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name      string
+		newWriter func(*bytes.Buffer) Writer
+		expected  []string
+	}{
+		{
+			"jsonl",
+			func(buf *bytes.Buffer) Writer { return NewJSONLWriter(buf) },
+			[]string{
+				`{"path":"a/b/loop.gif","size":13084,"verdict":"animated","format":"gif","kind":"animated","frames":17,"loops":-1,"width":242,"height":175,"duration":1.99}`,
+				`{"path":"a/b/readme.txt","size":12,"verdict":"unknown","frames":0,"loops":0,"width":0,"height":0,"duration":0,"error":"midec: unknown format"}`,
+			},
+		},
+		{
+			"csv",
+			func(buf *bytes.Buffer) Writer { return NewCSVWriter(buf) },
+			[]string{
+				"a/b/loop.gif,13084,animated,gif,animated,17,-1,242,175,1.99,",
+				"a/b/readme.txt,12,unknown,,,0,0,0,0,0,midec: unknown format",
+				"path,size,verdict,format,kind,frames,loops,width,height,duration,error",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w := tc.newWriter(&buf)
+			opts := Options{Include: []string{"loop.gif", "*.txt"}}
+			for res := range Scan(context.Background(), newTestFS(), ".", opts) {
+				if err := w.Write(res); err != nil {
+					t.Fatalf("Error = %v; want HasError = false", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Error = %v; want HasError = false", err)
+			}
+
+			// the order of the results is not stable
+			actual := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			sort.Strings(actual)
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Report = %q; want %q", actual, tc.expected)
+			}
+		})
+	}
+}
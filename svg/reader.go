@@ -0,0 +1,250 @@
+// Package svg implements an animated SVG (SMIL / CSS animation) detector
+package svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sapphi-red/midec"
+)
+
+// ErrNotSVG indicates that the root element was not svg.
+var ErrNotSVG = errors.New("midec: (svg) root element is not svg")
+
+var smilElements = map[string]struct{}{
+	"animate":          {},
+	"animateTransform": {},
+	"animateMotion":    {},
+	"animateColor":     {},
+	"set":              {},
+}
+
+var (
+	keyframesRule = regexp.MustCompile(`(?i)@(-[a-z]+-)?keyframes\b`)
+	// animationDecl captures the value of animation and animation-name properties
+	animationDecl = regexp.MustCompile(`(?i)(?:^|[\s;{])(?:-[a-z]+-)?animation(?:-name)?\s*:\s*([^;}]*)`)
+)
+
+const utf8BOM = "\xef\xbb\xbf"
+
+// prologEnd returns the length of the XML declaration, the processing instruction, the comment or
+// the document type declaration at the beginning of b. It is -1 when it does not end within b,
+// and 0 when b does not begin with any of them.
+func prologEnd(b []byte) int {
+	var end []byte
+	switch {
+	case bytes.HasPrefix(b, []byte("<?")):
+		end = []byte("?>")
+	case bytes.HasPrefix(b, []byte("<!--")):
+		end = []byte("-->")
+	case bytes.HasPrefix(b, []byte("<!DOCTYPE")):
+		// the internal subset may contain '>'
+		i := bytes.IndexAny(b, "[>")
+		if i >= 0 && b[i] == '[' {
+			j := bytes.Index(b[i:], []byte("]"))
+			if j < 0 {
+				return -1
+			}
+			i += j
+		}
+		if i < 0 {
+			return -1
+		}
+		j := bytes.IndexByte(b[i:], '>')
+		if j < 0 {
+			return -1
+		}
+		return i + j + 1
+	default:
+		return 0
+	}
+
+	i := bytes.Index(b, end)
+	if i < 0 {
+		return -1
+	}
+	return i + len(end)
+}
+
+// sniff reports whether b looks like the beginning of an SVG document, whose root element is svg.
+// The prolog is skipped to find the root element. When the prolog is longer than b, the root
+// element is unknown and it is not reported as SVG.
+func sniff(b []byte) bool {
+	b = bytes.TrimPrefix(b, []byte(utf8BOM))
+	for {
+		b = bytes.TrimLeft(b, " \t\r\n")
+		n := prologEnd(b)
+		if n < 0 {
+			return false
+		}
+		if n == 0 {
+			break
+		}
+		b = b[n:]
+	}
+
+	if !bytes.HasPrefix(b, []byte("<")) {
+		return false
+	}
+	name := b[1:]
+	i := bytes.IndexAny(name, " \t\r\n/>")
+	truncated := i < 0
+	if !truncated {
+		name = name[:i]
+	}
+	// the root element may have a namespace prefix, such as svg:svg
+	if i := bytes.LastIndexByte(name, ':'); i >= 0 {
+		name = name[i+1:]
+	}
+	if truncated {
+		return len(name) > 0 && bytes.HasPrefix([]byte("svg"), name)
+	}
+	return string(name) == "svg"
+}
+
+// isAnimatedCSS reports whether the style sheet or the style attribute has a CSS animation.
+func isAnimatedCSS(css string) bool {
+	if keyframesRule.MatchString(css) {
+		return true
+	}
+	for _, m := range animationDecl.FindAllStringSubmatch(css, -1) {
+		value := strings.ToLower(strings.TrimSpace(m[1]))
+		if value != "" && value != "none" && value != "initial" && value != "inherit" && value != "unset" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLength parses a length in user units, such as "100" or "100px". Other units are not supported.
+func parseLength(s string) (int, bool) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "px")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 {
+		return 0, false
+	}
+	return int(v + 0.5), true
+}
+
+// rootSize returns the size of the root svg element from width and height, or viewBox.
+// It is 0 when unknown.
+func rootSize(root xml.StartElement) (width, height int) {
+	var viewBox string
+	for _, attr := range root.Attr {
+		switch attr.Name.Local {
+		case "width":
+			width, _ = parseLength(attr.Value)
+		case "height":
+			height, _ = parseLength(attr.Value)
+		case "viewBox":
+			viewBox = attr.Value
+		}
+	}
+
+	fields := strings.FieldsFunc(viewBox, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	if len(fields) == 4 {
+		if width == 0 {
+			width, _ = parseLength(fields[2])
+		}
+		if height == 0 {
+			height, _ = parseLength(fields[3])
+		}
+	}
+	return width, height
+}
+
+type decoder struct {
+	*xml.Decoder
+}
+
+func newDecoder(r io.Reader) decoder {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	// SVG files are UTF-8 in practice, other charsets are read as is
+	d.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder{d}
+}
+
+// decode scans tokens and stops at the first SMIL element or CSS animation.
+// root is called with the root element.
+func (d decoder) decode(root func(xml.StartElement)) (bool, error) {
+	isRoot := true
+	inStyle := false
+	var style strings.Builder
+	for {
+		token, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				return false, nil
+			}
+			return false, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if isRoot {
+				if t.Name.Local != "svg" {
+					return false, ErrNotSVG
+				}
+				isRoot = false
+				if root != nil {
+					root(t)
+				}
+			}
+
+			if _, ok := smilElements[t.Name.Local]; ok {
+				return true, nil
+			}
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "style" && isAnimatedCSS(attr.Value) {
+					return true, nil
+				}
+			}
+			if t.Name.Local == "style" {
+				inStyle = true
+				style.Reset()
+			}
+		case xml.CharData:
+			if inStyle {
+				style.Write(t)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "style" && inStyle {
+				if isAnimatedCSS(style.String()) {
+					return true, nil
+				}
+				inStyle = false
+			}
+		}
+	}
+}
+
+func isAnimated(r io.Reader) (bool, error) {
+	return newDecoder(r).decode(nil)
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	info := &midec.Info{Kind: midec.KindStatic}
+	animated, err := newDecoder(r).decode(func(root xml.StartElement) {
+		info.Width, info.Height = rootSize(root)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if animated {
+		info.Kind = midec.KindAnimated
+	}
+	return info, nil
+}
+
+func init() {
+	midec.RegisterSniffedFormat("svg", sniff, isAnimated, inspect)
+}
@@ -0,0 +1,121 @@
+package svg
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sapphi-red/midec"
+)
+
+const testdataFolder = "../testdata/svg/"
+
+func Test_isAnimated(t *testing.T) {
+	t.Parallel()
+
+	runIsAnimated := func(filename string) (bool, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return isAnimated(fp)
+	}
+
+	testcases := []struct {
+		filename           string
+		expectedIsAnimated bool
+		expectedHasError   bool
+	}{
+		{"static.svg", false, false},
+		{"smil.svg", true, false},
+		{"smil-set.svg", true, false},
+		{"css-keyframes.svg", true, false},
+		{"css-attribute.svg", true, false},
+		{"invalid-root.svg", false, true},
+		{"invalid-syntax.svg", false, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actualIsAnimated, actualErr := runIsAnimated(tc.filename)
+			if tc.expectedIsAnimated != actualIsAnimated {
+				t.Errorf("IsAnimated = %t; want %t", actualIsAnimated, tc.expectedIsAnimated)
+			}
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+		})
+	}
+}
+
+func Test_sniff(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		input    string
+		expected bool
+	}{
+		{`<svg xmlns="http://www.w3.org/2000/svg"/>`, true},
+		{"\xef\xbb\xbf\n  <?xml version=\"1.0\"?><svg/>", true},
+		{`<?xml version="1.0"?><html/>`, false},
+		{`<!-- <html> --><!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "svg11.dtd"><svg>`, true},
+		{`<!DOCTYPE svg [<!ENTITY a "<b>">]><svg:svg xmlns:svg="http://www.w3.org/2000/svg">`, true},
+		{`<html><svg/></html>`, false},
+		{`<svgz/>`, false},
+		// the root element continues after the prefix
+		{`<!-- long --><sv`, true},
+		// the prolog continues after the prefix
+		{`<?xml version="1.0"?><!-- long`, false},
+		{`<?php echo "<svg/>";`, false},
+		{`svg`, false},
+		{``, false},
+	}
+
+	for _, tc := range testcases {
+		if actual := sniff([]byte(tc.input)); actual != tc.expected {
+			t.Errorf("sniff(%q) = %t; want %t", tc.input, actual, tc.expected)
+		}
+	}
+}
+
+func Test_inspect(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		input            string
+		expected         *midec.Info
+		expectedHasError bool
+	}{
+		{`<svg width="16" height="16"><animate/></svg>`, &midec.Info{Kind: midec.KindAnimated, Width: 16, Height: 16}, false},
+		{`<svg width="100px" height="50.4px"/>`, &midec.Info{Kind: midec.KindStatic, Width: 100, Height: 50}, false},
+		{`<svg width="100%" viewBox="0 0 32,24"/>`, &midec.Info{Kind: midec.KindStatic, Width: 32, Height: 24}, false},
+		{`<svg width="10em"/>`, &midec.Info{Kind: midec.KindStatic}, false},
+		{`<html/>`, nil, true},
+	}
+
+	for _, tc := range testcases {
+		actual, actualErr := inspect(strings.NewReader(tc.input))
+		if tc.expectedHasError != (actualErr != nil) {
+			t.Errorf("inspect(%q): Error = %v; want HasError = %t", tc.input, actualErr, tc.expectedHasError)
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("inspect(%q): Info = %+v; want %+v", tc.input, actual, tc.expected)
+		}
+	}
+}
+
+func Test_Inspect_LongProlog(t *testing.T) {
+	t.Parallel()
+
+	// the root element is after the first 1024 bytes
+	input := `<?xml version="1.0"?><!--` + strings.Repeat(" license", 200) + ` --><svg width="16" height="16"><set/></svg>`
+
+	_, actualErr := midec.Inspect(strings.NewReader(input))
+	if actualErr != midec.ErrFormat {
+		t.Errorf("Error = %v; want %v", actualErr, midec.ErrFormat)
+	}
+}
@@ -0,0 +1,268 @@
+// Package tiff implements a Multi-page TIFF / BigTIFF detector
+package tiff
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/sapphi-red/midec"
+)
+
+const (
+	leHeader        = "II*\x00"
+	beHeader        = "MM\x00*"
+	leBigTIFFHeader = "II+\x00"
+	beBigTIFFHeader = "MM\x00+"
+)
+
+// ErrInvalidHeader indicates that the header was broken.
+var ErrInvalidHeader = errors.New("midec: (tiff) invalid header")
+
+// ErrNoIFD indicates that the offset of the first IFD was 0, so the file had no images.
+var ErrNoIFD = errors.New("midec: (tiff) no IFD")
+
+// ErrIFDLoop indicates that the IFD chain had a loop.
+var ErrIFDLoop = errors.New("midec: (tiff) IFD chain has a loop")
+
+const (
+	tagNewSubfileType = 254
+	tagSubfileType    = 255
+)
+
+const (
+	typeShort = 3
+	typeLong  = 4
+	typeLong8 = 16
+)
+
+const (
+	maskNewSubfileTypeReducedResolution = 1 << 0
+
+	subfileTypeReducedResolution = 2
+)
+
+type ifdData struct {
+	isReducedResolution bool
+	nextOffset          int64
+}
+
+type decoder struct {
+	midec.ReadAdvancer
+	byteOrder binary.ByteOrder
+	bigTIFF   bool
+}
+
+func (d *decoder) read(data interface{}) error {
+	return d.ReadBinary(d.byteOrder, data)
+}
+
+func (d *decoder) readOffset() (int64, error) {
+	if !d.bigTIFF {
+		var offset uint32
+		err := d.read(&offset)
+		return int64(offset), err
+	}
+
+	var offset uint64
+	if err := d.read(&offset); err != nil {
+		return 0, err
+	}
+	if offset > math.MaxInt64 {
+		return 0, ErrInvalidHeader
+	}
+	return int64(offset), nil
+}
+
+func (d *decoder) readCount() (uint64, error) {
+	if !d.bigTIFF {
+		var count uint16
+		err := d.read(&count)
+		return uint64(count), err
+	}
+
+	var count uint64
+	err := d.read(&count)
+	return count, err
+}
+
+// decodeHeader reads the header and returns the offset of the first IFD.
+func (d *decoder) decodeHeader() (int64, error) {
+	byteOrderBuf, err := d.Next(2)
+	if err != nil {
+		return 0, err
+	}
+
+	switch string(byteOrderBuf) {
+	case "II":
+		d.byteOrder = binary.LittleEndian
+	case "MM":
+		d.byteOrder = binary.BigEndian
+	default:
+		return 0, ErrInvalidHeader
+	}
+
+	var version uint16
+	if err := d.read(&version); err != nil {
+		return 0, err
+	}
+
+	switch version {
+	case 42:
+		d.bigTIFF = false
+	case 43:
+		d.bigTIFF = true
+
+		var offsetSize, reserved uint16
+		if err := d.read(&offsetSize); err != nil {
+			return 0, err
+		}
+		if err := d.read(&reserved); err != nil {
+			return 0, err
+		}
+		if offsetSize != 8 || reserved != 0 {
+			return 0, ErrInvalidHeader
+		}
+	default:
+		return 0, ErrInvalidHeader
+	}
+
+	return d.readOffset()
+}
+
+func (d *decoder) decodeEntryValue(fieldType uint16, value []byte) uint64 {
+	switch fieldType {
+	case typeShort:
+		return uint64(d.byteOrder.Uint16(value))
+	case typeLong:
+		return uint64(d.byteOrder.Uint32(value))
+	case typeLong8:
+		if len(value) >= 8 {
+			return d.byteOrder.Uint64(value)
+		}
+	}
+	return 0
+}
+
+func (d *decoder) decodeIFD(offset int64) (ifdd ifdData, err error) {
+	if err = d.SeekTo(offset); err != nil {
+		return
+	}
+
+	count, err := d.readCount()
+	if err != nil {
+		return
+	}
+
+	countSize := uint(4)
+	valueBuf := make([]byte, 4)
+	if d.bigTIFF {
+		countSize = 8
+		valueBuf = make([]byte, 8)
+	}
+
+	for i := uint64(0); i < count; i++ {
+		var tag, fieldType uint16
+		if err = d.read(&tag); err != nil {
+			return
+		}
+		if err = d.read(&fieldType); err != nil {
+			return
+		}
+		if err = d.Advance(countSize); err != nil {
+			return
+		}
+		if _, err = d.ReadFull(valueBuf); err != nil {
+			return
+		}
+
+		switch tag {
+		case tagNewSubfileType:
+			v := d.decodeEntryValue(fieldType, valueBuf)
+			if v&maskNewSubfileTypeReducedResolution != 0 {
+				ifdd.isReducedResolution = true
+			}
+		case tagSubfileType:
+			v := d.decodeEntryValue(fieldType, valueBuf)
+			if v == subfileTypeReducedResolution {
+				ifdd.isReducedResolution = true
+			}
+		}
+	}
+
+	ifdd.nextOffset, err = d.readOffset()
+	return
+}
+
+// countPages counts top-level images except reduced-resolution ones.
+// It stops counting when the count reaches limit, unless limit is 0.
+func (d *decoder) countPages(limit int) (int, error) {
+	offset, err := d.decodeHeader()
+	if err != nil {
+		return 0, err
+	}
+	if offset == 0 {
+		return 0, ErrNoIFD
+	}
+
+	visited := make(map[int64]struct{})
+	count := 0
+	for offset != 0 {
+		if _, ok := visited[offset]; ok {
+			return count, ErrIFDLoop
+		}
+		visited[offset] = struct{}{}
+
+		ifdd, err := d.decodeIFD(offset)
+		if err != nil {
+			return count, err
+		}
+
+		if !ifdd.isReducedResolution {
+			count++
+			if limit > 0 && count >= limit {
+				return count, nil
+			}
+		}
+		offset = ifdd.nextOffset
+	}
+	return count, nil
+}
+
+func (d *decoder) decode() (bool, error) {
+	count, err := d.countPages(2)
+	if err != nil {
+		return false, err
+	}
+	return count >= 2, nil
+}
+
+func (d *decoder) inspect() (*midec.Info, error) {
+	count, err := d.countPages(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if count >= 2 {
+		return &midec.Info{Kind: midec.KindMultiImage, Frames: count}, nil
+	}
+	return &midec.Info{Kind: midec.KindStatic, Frames: count}, nil
+}
+
+func isAnimated(r io.Reader) (bool, error) {
+	d := decoder{ReadAdvancer: *midec.NewReadAdvancer(r)}
+	return d.decode()
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{ReadAdvancer: *midec.NewReadAdvancer(r)}
+	return d.inspect()
+}
+
+func init() {
+	midec.RegisterInspectableFormat("tiff", leHeader, isAnimated, inspect)
+	midec.RegisterInspectableFormat("tiff", beHeader, isAnimated, inspect)
+	midec.RegisterInspectableFormat("tiff", leBigTIFFHeader, isAnimated, inspect)
+	midec.RegisterInspectableFormat("tiff", beBigTIFFHeader, isAnimated, inspect)
+}
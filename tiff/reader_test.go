@@ -0,0 +1,72 @@
+package tiff
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/sapphi-red/midec"
+)
+
+const testdataFolder = "../testdata/tiff/"
+
+func Test_isAnimated(t *testing.T) {
+	t.Parallel()
+
+	runIsAnimated := func(filename string) (bool, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return isAnimated(fp)
+	}
+
+	testcases := []struct {
+		filename           string
+		expectedIsAnimated bool
+		expectedHasError   bool
+	}{
+		{"multipage.tif", true, false},
+		{"multipage-be.tif", true, false},
+		{"multipage-bigtiff.tif", true, false},
+		{"multipage-bigtiff-be.tif", true, false},
+		{"multipage-backward.tif", true, false},
+		{"static.tif", false, false},
+		{"static-bigtiff.tif", false, false},
+		{"static-reduced.tif", false, false},
+		{"static-subfiletype.tif", false, false},
+		{"invalid-header.tif", false, true},
+		{"invalid-version.tif", false, true},
+		{"invalid-ifd.tif", false, true},
+		{"invalid-ifd-loop.tif", false, true},
+		{"invalid-noifd.tif", false, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actualIsAnimated, actualErr := runIsAnimated(tc.filename)
+			if tc.expectedIsAnimated != actualIsAnimated {
+				t.Errorf("IsAnimated = %t; want %t", actualIsAnimated, tc.expectedIsAnimated)
+			}
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+		})
+	}
+}
+
+func Test_isAnimated_NotSeekable(t *testing.T) {
+	fp, err := os.Open(testdataFolder + "multipage-backward.tif")
+	if err != nil {
+		panic(err)
+	}
+
+	_, actualErr := isAnimated(struct{ io.Reader }{fp})
+	if !errors.Is(actualErr, midec.ErrNotSeekable) {
+		t.Errorf("Error = %v; want %v", actualErr, midec.ErrNotSeekable)
+	}
+}
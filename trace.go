@@ -0,0 +1,44 @@
+package midec
+
+import (
+	"io"
+)
+
+// Structure is a block, a chunk or a box read by a decoder.
+type Structure struct {
+	// Type is the name of the block, the chunk type or the box type.
+	Type string
+	// Offset is the position of the structure from the beginning of the file.
+	Offset int64
+	// Size includes the header. It is -1 when the structure extends to the end of the file.
+	Size int64
+	// Depth is the nesting level. It is 0 for the top-level structures.
+	Depth int
+}
+
+// Visitor is called with each structure in the order the decoder reads them.
+type Visitor func(Structure)
+
+// traceReader carries the Visitor to the decoders through the io.Reader passed to them.
+type traceReader struct {
+	reader
+	visit Visitor
+}
+
+// Seek implements io.Seeker when the underlying reader does.
+func (t *traceReader) Seek(offset int64, whence int) (int64, error) {
+	s, ok := t.reader.(io.Seeker)
+	if !ok {
+		return 0, ErrNotSeekable
+	}
+	return s.Seek(offset, whence)
+}
+
+// Trace is the same as Inspect but calls visit with each structure read by the decoder.
+// The decoders of GIF, PNG (MNG, JNG), RIFF based formats (WebP, ANI, DjVu) and ISOBMFF report the structures.
+// Other formats are inspected without calling visit.
+func Trace(r io.Reader, visit Visitor) (*Info, error) {
+	rr, pooled := asReader(r)
+	defer releaseReader(pooled)
+	return Inspect(&traceReader{rr, visit})
+}
@@ -0,0 +1,124 @@
+package midec_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sapphi-red/midec"
+)
+
+func Test_Trace(t *testing.T) {
+	t.Parallel()
+
+	// only the first limit structures are kept
+	runTrace := func(filename string, limit int) ([]midec.Structure, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+
+		var structures []midec.Structure
+		_, err = midec.Trace(fp, func(s midec.Structure) {
+			if len(structures) < limit {
+				structures = append(structures, s)
+			}
+		})
+		return structures, err
+	}
+
+	testcases := []struct {
+		filename         string
+		expected         []midec.Structure
+		expectedHasError bool
+	}{
+		{
+			"gif/loop.gif",
+			[]midec.Structure{
+				{Type: "Header", Offset: 0, Size: 13, Depth: 0},
+				{Type: "Application Extension", Offset: 13, Size: 19, Depth: 0},
+				{Type: "Graphic Control Extension", Offset: 32, Size: 8, Depth: 0},
+				{Type: "Image", Offset: 40, Size: 5022, Depth: 0},
+			},
+			false,
+		},
+		{
+			"png/static.png",
+			[]midec.Structure{
+				{Type: "signature", Offset: 0, Size: 8, Depth: 0},
+				{Type: "IHDR", Offset: 8, Size: 25, Depth: 0},
+				{Type: "sRGB", Offset: 33, Size: 13, Depth: 0},
+				{Type: "gAMA", Offset: 46, Size: 16, Depth: 0},
+			},
+			false,
+		},
+		{
+			"webp/animated.webp",
+			[]midec.Structure{
+				{Type: "RIFF WEBP", Offset: 0, Size: 17602, Depth: 0},
+				{Type: "VP8X", Offset: 12, Size: 18, Depth: 1},
+				{Type: "ANIM", Offset: 30, Size: 14, Depth: 1},
+				{Type: "ANMF", Offset: 44, Size: 4488, Depth: 1},
+			},
+			false,
+		},
+		{
+			"isobmff/movie.mp4",
+			[]midec.Structure{
+				{Type: "ftyp", Offset: 0, Size: 32, Depth: 0},
+				{Type: "free", Offset: 32, Size: 8, Depth: 0},
+				{Type: "mdat", Offset: 40, Size: 6404, Depth: 0},
+				{Type: "moov", Offset: 6444, Size: 1669, Depth: 0},
+				{Type: "mvhd", Offset: 6452, Size: 108, Depth: 1},
+				{Type: "trak", Offset: 6560, Size: 1455, Depth: 1},
+				{Type: "tkhd", Offset: 6568, Size: 92, Depth: 2},
+				{Type: "edts", Offset: 6660, Size: 36, Depth: 2},
+				{Type: "mdia", Offset: 6696, Size: 1319, Depth: 2},
+				{Type: "mdhd", Offset: 6704, Size: 32, Depth: 3},
+				{Type: "hdlr", Offset: 6736, Size: 45, Depth: 3},
+				{Type: "udta", Offset: 8015, Size: 98, Depth: 1},
+			},
+			false,
+		},
+		{
+			"djvu/multipage.djvu",
+			[]midec.Structure{
+				{Type: "FORM DJVM", Offset: 4, Size: 204, Depth: 0},
+				{Type: "DIRM", Offset: 16, Size: 36, Depth: 1},
+				{Type: "FORM", Offset: 52, Size: 24, Depth: 1},
+			},
+			false,
+		},
+		{
+			"gif/invalid-block-unknown.gif",
+			[]midec.Structure{
+				{Type: "Header", Offset: 0, Size: 781, Depth: 0},
+			},
+			true,
+		},
+		// not supported yet
+		{"jpeg/static.jpg", []midec.Structure{}, false},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			limit := len(tc.expected)
+			if limit == 0 {
+				limit = 1
+			}
+			actual, actualErr := runTrace(tc.filename, limit)
+			if actual == nil {
+				actual = []midec.Structure{}
+			}
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Structures = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+// Package ugoira implements a Pixiv Ugoira (zip of frames) detector
+package ugoira
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sapphi-red/midec"
+)
+
+const zipHeader = "PK\x03\x04"
+
+const animationName = "animation.json"
+
+// ErrNotUgoira indicates that the zip had entries other than frames and animation.json, had no frames,
+// or had frames not named in sequence without animation.json.
+var ErrNotUgoira = errors.New("midec: (ugoira) not a zip of frames")
+
+// ErrInvalidAnimation indicates that animation.json was broken or referred to a missing frame.
+var ErrInvalidAnimation = errors.New("midec: (ugoira) invalid animation.json")
+
+// maxAnimationSize is the maximum size of animation.json to be read into memory.
+const maxAnimationSize = 1 << 20
+
+// frameNumberDigits is the length of the frame names without the extension.
+const frameNumberDigits = 6
+
+const (
+	localFileHeaderSize   = 30
+	localFileNameLenStart = 26
+)
+
+var frameExtensions = map[string]struct{}{
+	".jpg":  {},
+	".jpeg": {},
+	".png":  {},
+	".gif":  {},
+}
+
+// Frame is a frame entry in the zip.
+type Frame struct {
+	Name string
+	// Delay is 0 when there was no animation.json.
+	Delay time.Duration
+}
+
+// Animation is the information read from the zip.
+type Animation struct {
+	// Frames is in the order of animation.json, or in the order of names when there was no animation.json.
+	Frames []Frame
+	// HasDelays is true when the delays were read from animation.json.
+	HasDelays bool
+}
+
+// Duration returns the time taken by one cycle.
+func (a *Animation) Duration() time.Duration {
+	total := time.Duration(0)
+	for _, f := range a.Frames {
+		total += f.Delay
+	}
+	return total
+}
+
+// animationData is animation.json. The frames may be wrapped by the response of the Pixiv API.
+type animationData struct {
+	Frames []frameData `json:"frames"`
+	Body   *struct {
+		Frames []frameData `json:"frames"`
+	} `json:"body"`
+	UgoiraMeta *struct {
+		Frames []frameData `json:"frames"`
+	} `json:"ugoira_meta"`
+}
+
+type frameData struct {
+	File  string `json:"file"`
+	Delay int    `json:"delay"` // ms
+}
+
+func (ad *animationData) frames() []frameData {
+	switch {
+	case ad.Frames != nil:
+		return ad.Frames
+	case ad.Body != nil:
+		return ad.Body.Frames
+	case ad.UgoiraMeta != nil:
+		return ad.UgoiraMeta.Frames
+	}
+	return nil
+}
+
+func isFrameName(name string) bool {
+	_, ok := frameExtensions[strings.ToLower(path.Ext(name))]
+	return ok
+}
+
+// frameNumber returns the number of a frame named as by Pixiv, such as 000000.jpg.
+// It is -1 for the other names.
+func frameNumber(name string) int {
+	ext := strings.ToLower(path.Ext(name))
+	if ext != ".jpg" && ext != ".png" {
+		return -1
+	}
+	base := strings.TrimSuffix(name, path.Ext(name))
+	if len(base) != frameNumberDigits {
+		return -1
+	}
+	n := 0
+	for _, c := range base {
+		if c < '0' || c > '9' {
+			return -1
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n
+}
+
+// sniff reports whether the first entry of the zip is animation.json or a frame named as by Pixiv,
+// so that other zips of images are not detected.
+func sniff(b []byte) bool {
+	if !bytes.HasPrefix(b, []byte(zipHeader)) || len(b) < localFileHeaderSize {
+		return false
+	}
+
+	nameLen := int(binary.LittleEndian.Uint16(b[localFileNameLenStart:]))
+	if len(b) < localFileHeaderSize+nameLen {
+		return false
+	}
+	name := string(b[localFileHeaderSize : localFileHeaderSize+nameLen])
+	return name == animationName || frameNumber(name) >= 0
+}
+
+func decodeAnimationFile(f *zip.File) ([]frameData, error) {
+	if f.UncompressedSize64 > maxAnimationSize {
+		return nil, ErrInvalidAnimation
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var ad animationData
+	if err := json.NewDecoder(io.LimitReader(rc, maxAnimationSize)).Decode(&ad); err != nil {
+		return nil, ErrInvalidAnimation
+	}
+	frames := ad.frames()
+	if len(frames) == 0 {
+		return nil, ErrInvalidAnimation
+	}
+	return frames, nil
+}
+
+// DecodeAnimation lists the frames in the zip and reads the delays from animation.json if exists.
+func DecodeAnimation(r io.ReaderAt, size int64) (*Animation, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	var animationFile *zip.File
+	for _, f := range zr.File {
+		switch {
+		case strings.HasSuffix(f.Name, "/"):
+			// directory
+		case f.Name == animationName:
+			animationFile = f
+		case isFrameName(f.Name):
+			names = append(names, f.Name)
+		default:
+			return nil, ErrNotUgoira
+		}
+	}
+	if len(names) == 0 {
+		return nil, ErrNotUgoira
+	}
+
+	if animationFile == nil {
+		// without animation.json, the frames are only told from other images by the names
+		sort.Strings(names)
+		a := &Animation{Frames: make([]Frame, len(names))}
+		for i, name := range names {
+			if frameNumber(name) != i {
+				return nil, ErrNotUgoira
+			}
+			a.Frames[i].Name = name
+		}
+		return a, nil
+	}
+
+	frames, err := decodeAnimationFile(animationFile)
+	if err != nil {
+		return nil, err
+	}
+
+	exists := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		exists[name] = struct{}{}
+	}
+	a := &Animation{Frames: make([]Frame, len(frames)), HasDelays: true}
+	for i, f := range frames {
+		if _, ok := exists[f.File]; !ok {
+			return nil, ErrInvalidAnimation
+		}
+		a.Frames[i] = Frame{Name: f.File, Delay: time.Duration(f.Delay) * time.Millisecond}
+	}
+	return a, nil
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	ra, size, err := midec.AsReaderAt(r)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := DecodeAnimation(ra, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(a.Frames) >= 2 {
+		return &midec.Info{Kind: midec.KindAnimated, Frames: len(a.Frames), Duration: a.Duration()}, nil
+	}
+	return &midec.Info{Kind: midec.KindStatic, Frames: len(a.Frames)}, nil
+}
+
+func init() {
+	midec.RegisterSniffedFormat("ugoira", sniff, nil, inspect)
+}
@@ -0,0 +1,117 @@
+package ugoira
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sapphi-red/midec"
+)
+
+const testdataFolder = "../testdata/ugoira/"
+
+func Test_inspect(t *testing.T) {
+	t.Parallel()
+
+	runInspect := func(filename string) (*midec.Info, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return inspect(fp)
+	}
+
+	testcases := []struct {
+		filename         string
+		expected         *midec.Info
+		expectedHasError bool
+	}{
+		{"animated.zip", &midec.Info{Kind: midec.KindAnimated, Frames: 3, Duration: 500 * time.Millisecond}, false},
+		{"animated-noanimation.zip", &midec.Info{Kind: midec.KindAnimated, Frames: 4}, false},
+		{"static.zip", &midec.Info{Kind: midec.KindStatic, Frames: 1}, false},
+		{"invalid-entry.zip", nil, true},
+		{"invalid-animation.zip", nil, true},
+		// zips of images which are not named as frames
+		{"invalid-names.zip", nil, true},
+		{"invalid-sequence.zip", nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runInspect(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Info = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_inspect_NotSeekable(t *testing.T) {
+	t.Parallel()
+
+	fp, err := os.Open(testdataFolder + "animated.zip")
+	if err != nil {
+		panic(err)
+	}
+
+	if _, err := inspect(bufio.NewReader(fp)); err != midec.ErrNotSeekable {
+		t.Errorf("Error = %v; want %v", err, midec.ErrNotSeekable)
+	}
+}
+
+func Test_DecodeAnimation(t *testing.T) {
+	t.Parallel()
+
+	runDecodeAnimation := func(filename string) (*Animation, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		stat, err := fp.Stat()
+		if err != nil {
+			panic(err)
+		}
+		return DecodeAnimation(fp, stat.Size())
+	}
+
+	ms := time.Millisecond
+	testcases := []struct {
+		filename string
+		expected *Animation
+	}{
+		{"animated.zip", &Animation{
+			Frames:    []Frame{{"000000.jpg", 100 * ms}, {"000001.jpg", 150 * ms}, {"000002.jpg", 250 * ms}},
+			HasDelays: true,
+		}},
+		{"animated-noanimation.zip", &Animation{
+			Frames: []Frame{{Name: "000000.png"}, {Name: "000001.png"}, {Name: "000002.png"}, {Name: "000003.png"}},
+		}},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runDecodeAnimation(tc.filename)
+			if actualErr != nil {
+				t.Fatalf("Error = %v; want HasError = false", actualErr)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Animation = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}
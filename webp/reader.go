@@ -3,9 +3,12 @@ package webp
 
 import (
 	"encoding/binary"
+	"errors"
 	"io"
+	"time"
 
 	"github.com/sapphi-red/midec"
+	"github.com/sapphi-red/midec/internal/riff"
 )
 
 const webpHeader = "RIFF????WEBPVP8"
@@ -14,58 +17,36 @@ const (
 	maskVP8XAnimation = 1 << 1
 )
 
-type chunkHeaderData struct {
-	fourCC   string
-	dataSize uint32
-}
+// ErrInvalidChunk indicates that VP8X, ANIM, ANMF, VP8 or VP8L chunk was too short.
+var ErrInvalidChunk = errors.New("midec: (webp) invalid chunk")
 
-type decoder struct {
-	midec.ReadAdvancer
-}
+const (
+	vp8xSize = 10
+	animSize = 6
+	anmfSize = 16
+	vp8Size  = 10
+	vp8lSize = 5
 
-func (d *decoder) readUint32() (u uint32, err error) {
-	err = binary.Read(d.Reader, binary.LittleEndian, &u)
-	return
-}
+	mask14bits = 0x3fff
+)
 
-func (d *decoder) skipHeader() error {
-	return d.Advance(
-		4 + // 'RIFF'
-			4 + // File size
-			4, // 'WEBP'
-	)
+func readUint24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
 }
 
-func (d *decoder) decodeChunkHeader() (chd chunkHeaderData, err error) {
-	fourCCBuf := make([]byte, 4)
-	_, err = d.ReadFull(fourCCBuf)
-	if err != nil {
-		return
-	}
-
-	dataSize, err := d.readUint32()
-	if err != nil {
-		if err == io.EOF {
-			err = io.ErrUnexpectedEOF
-		}
-		return
-	}
-
-	return chunkHeaderData{
-		fourCC:   string(fourCCBuf),
-		dataSize: dataSize,
-	}, nil
+type decoder struct {
+	riff.Decoder
 }
 
 func (d *decoder) decodeVP8XChunk() (bool, error) {
-	buf := make([]byte, 1)
-	if _, err := d.ReadFull(buf); err != nil {
+	buf, err := d.Next(1)
+	if err != nil {
 		return false, err
 	}
 
 	isAnimation := (buf[0] & maskVP8XAnimation) != 0
 
-	err := d.Advance(
+	err = d.Advance(
 		3 + // Reserved
 			3 + // Canvas Width Minus One
 			3, // Canvas Height Minus One
@@ -77,21 +58,17 @@ func (d *decoder) decodeVP8XChunk() (bool, error) {
 	return isAnimation, nil
 }
 
-func (d *decoder) skipThisChunk(dataSize uint32) error {
-	return d.Advance(uint(dataSize))
-}
-
 func (d *decoder) decode() (bool, error) {
-	if err := d.skipHeader(); err != nil {
+	if _, err := d.DecodeFormHeader(); err != nil {
 		return false, err
 	}
 
-	chd, err := d.decodeChunkHeader()
+	chd, err := d.DecodeChunkHeader()
 	if err != nil {
 		return false, err
 	}
 
-	if chd.fourCC != "VP8X" {
+	if chd.FourCC != "VP8X" {
 		return false, nil
 	}
 
@@ -105,7 +82,7 @@ func (d *decoder) decode() (bool, error) {
 
 	frameCount := 0
 	for {
-		chd, err := d.decodeChunkHeader()
+		chd, err := d.DecodeChunkHeader()
 		if err != nil {
 			if err == io.EOF {
 				return false, nil
@@ -113,24 +90,139 @@ func (d *decoder) decode() (bool, error) {
 			return false, err
 		}
 
-		if chd.fourCC == "ANMF" {
+		if chd.FourCC == "ANMF" {
 			frameCount++
 			if frameCount >= 2 {
 				return true, nil
 			}
 		}
 
-		if err := d.skipThisChunk(chd.dataSize); err != nil {
+		if err := d.SkipChunk(chd); err != nil {
 			return false, err
 		}
 	}
 }
 
+// readChunkData reads the first size bytes of the chunk data and skips the rest.
+func (d *decoder) readChunkData(chd riff.ChunkHeaderData, size uint32) ([]byte, error) {
+	if chd.DataSize < size {
+		return nil, ErrInvalidChunk
+	}
+
+	buf := make([]byte, size)
+	if _, err := d.ReadFull(buf); err != nil {
+		return nil, err
+	}
+	err := d.Advance(uint(chd.DataSize-size) + uint(chd.DataSize&1))
+	return buf, err
+}
+
+// decodeSimpleFormat reads the size of a lossy or lossless image without VP8X chunk.
+func (d *decoder) decodeSimpleFormat(chd riff.ChunkHeaderData) (*midec.Info, error) {
+	info := &midec.Info{Kind: midec.KindStatic, Frames: 1}
+	switch chd.FourCC {
+	case "VP8 ":
+		data, err := d.readChunkData(chd, vp8Size)
+		if err != nil {
+			return nil, err
+		}
+		// after the frame tag and the start code
+		info.Width = int(binary.LittleEndian.Uint16(data[6:8]) & mask14bits)
+		info.Height = int(binary.LittleEndian.Uint16(data[8:10]) & mask14bits)
+	case "VP8L":
+		data, err := d.readChunkData(chd, vp8lSize)
+		if err != nil {
+			return nil, err
+		}
+		// after the signature
+		bits := binary.LittleEndian.Uint32(data[1:5])
+		info.Width = int(bits&mask14bits) + 1
+		info.Height = int(bits>>14&mask14bits) + 1
+	}
+	return info, nil
+}
+
+func (d *decoder) inspect() (*midec.Info, error) {
+	if _, err := d.DecodeFormHeader(); err != nil {
+		return nil, err
+	}
+
+	chd, err := d.DecodeChunkHeader()
+	if err != nil {
+		return nil, err
+	}
+	if chd.FourCC != "VP8X" {
+		return d.decodeSimpleFormat(chd)
+	}
+
+	data, err := d.readChunkData(chd, vp8xSize)
+	if err != nil {
+		return nil, err
+	}
+	info := &midec.Info{
+		Kind:   midec.KindStatic,
+		Frames: 1,
+		Width:  readUint24(data[4:7]) + 1,
+		Height: readUint24(data[7:10]) + 1,
+	}
+	if data[0]&maskVP8XAnimation == 0 {
+		return info, nil
+	}
+
+	info.Frames = 0
+	for {
+		chd, err := d.DecodeChunkHeader()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		switch chd.FourCC {
+		case "ANIM":
+			data, err := d.readChunkData(chd, animSize)
+			if err != nil {
+				return nil, err
+			}
+			info.Loops = int(binary.LittleEndian.Uint16(data[4:6]))
+			if info.Loops == 0 {
+				info.Loops = midec.LoopInfinite
+			}
+		case "ANMF":
+			// the frame data is skipped together
+			data, err := d.readChunkData(chd, anmfSize)
+			if err != nil {
+				return nil, err
+			}
+			info.Frames++
+			info.Duration += time.Duration(readUint24(data[12:15])) * time.Millisecond
+		default:
+			if err := d.SkipChunk(chd); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if info.Frames >= 2 {
+		info.Kind = midec.KindAnimated
+	} else {
+		info.Loops = 0
+		info.Duration = 0
+	}
+	return info, nil
+}
+
 func isAnimated(r io.Reader) (bool, error) {
-	d := decoder{*midec.NewReadAdvancer(r)}
+	d := decoder{*riff.NewDecoder(r, binary.LittleEndian)}
 	return d.decode()
 }
 
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{*riff.NewDecoder(r, binary.LittleEndian)}
+	return d.inspect()
+}
+
 func init() {
-	midec.RegisterFormat("webp", webpHeader, isAnimated)
+	midec.RegisterInspectableFormat("webp", webpHeader, isAnimated, inspect)
 }
@@ -0,0 +1,231 @@
+// Package xcursor implements an animated X11 cursor (Xcursor) detector
+package xcursor
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/sapphi-red/midec"
+)
+
+const xcursorHeader = "Xcur"
+
+// ErrInvalidHeader indicates that the file header was broken.
+var ErrInvalidHeader = errors.New("midec: (xcursor) invalid header")
+
+// ErrNoImage indicates that the table of contents did not have any image entries.
+var ErrNoImage = errors.New("midec: (xcursor) no image in table of contents")
+
+// ErrInvalidChunk indicates that the chunk pointed by an entry did not match the entry.
+var ErrInvalidChunk = errors.New("midec: (xcursor) invalid image chunk")
+
+const (
+	fileHeaderSize  = 16
+	imageHeaderSize = 36
+
+	chunkTypeImage = 0xfffd0002
+)
+
+// maxTOCEntries is the maximum number of entries in the table of contents, same as libXcursor.
+const maxTOCEntries = 0x10000
+
+// Size is the frames of a nominal size.
+type Size struct {
+	NominalSize int
+	// Frames is the number of images of this nominal size.
+	Frames int
+	// Delays is the delay of each frame.
+	Delays []time.Duration
+}
+
+// Duration returns the time taken by one cycle.
+func (s *Size) Duration() time.Duration {
+	total := time.Duration(0)
+	for _, d := range s.Delays {
+		total += d
+	}
+	return total
+}
+
+// Cursor is the information read from the table of contents and the image chunks.
+type Cursor struct {
+	// Sizes is in the order of first appearance in the table of contents.
+	Sizes []Size
+}
+
+type tocEntryData struct {
+	chunkType uint32
+	subtype   uint32
+	position  uint32
+}
+
+type decoder struct {
+	midec.ReadAdvancer
+}
+
+func (d *decoder) read(data interface{}) error {
+	return d.ReadBinary(binary.LittleEndian, data)
+}
+
+// decodeTOC reads the file header and returns the image entries in the table of contents.
+func (d *decoder) decodeTOC() ([]tocEntryData, error) {
+	var header struct {
+		Magic      [4]byte
+		HeaderSize uint32
+		Version    uint32
+		NTOC       uint32
+	}
+	if err := d.read(&header); err != nil {
+		return nil, err
+	}
+	if string(header.Magic[:]) != xcursorHeader || header.HeaderSize < fileHeaderSize || header.NTOC > maxTOCEntries {
+		return nil, ErrInvalidHeader
+	}
+	if err := d.Advance(uint(header.HeaderSize - fileHeaderSize)); err != nil {
+		return nil, err
+	}
+
+	var entries []tocEntryData
+	for i := uint32(0); i < header.NTOC; i++ {
+		var e tocEntryData
+		if err := d.read(&e.chunkType); err != nil {
+			return nil, err
+		}
+		if err := d.read(&e.subtype); err != nil {
+			return nil, err
+		}
+		if err := d.read(&e.position); err != nil {
+			return nil, err
+		}
+
+		if e.chunkType == chunkTypeImage {
+			entries = append(entries, e)
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrNoImage
+	}
+	return entries, nil
+}
+
+// decodeImageDelay reads the image chunk header pointed by e and returns its delay.
+func (d *decoder) decodeImageDelay(e tocEntryData) (time.Duration, error) {
+	if err := d.SeekTo(int64(e.position)); err != nil {
+		return 0, err
+	}
+
+	var chunk struct {
+		HeaderSize uint32
+		Type       uint32
+		Subtype    uint32
+		Version    uint32
+		Width      uint32
+		Height     uint32
+		XHot       uint32
+		YHot       uint32
+		Delay      uint32
+	}
+	if err := d.read(&chunk); err != nil {
+		return 0, err
+	}
+	if chunk.HeaderSize != imageHeaderSize || chunk.Type != e.chunkType || chunk.Subtype != e.subtype {
+		return 0, ErrInvalidChunk
+	}
+	return time.Duration(chunk.Delay) * time.Millisecond, nil
+}
+
+// groupBySize groups the entries by the nominal size, in the order of first appearance.
+func groupBySize(entries []tocEntryData) (sizes []Size, indexes []int) {
+	indexOfSize := make(map[uint32]int)
+	indexes = make([]int, len(entries))
+	for i, e := range entries {
+		idx, ok := indexOfSize[e.subtype]
+		if !ok {
+			idx = len(sizes)
+			indexOfSize[e.subtype] = idx
+			sizes = append(sizes, Size{NominalSize: int(e.subtype)})
+		}
+		sizes[idx].Frames++
+		indexes[i] = idx
+	}
+	return
+}
+
+func (d *decoder) decodeCursor() (*Cursor, error) {
+	entries, err := d.decodeTOC()
+	if err != nil {
+		return nil, err
+	}
+
+	sizes, indexes := groupBySize(entries)
+	delays := make([]time.Duration, len(entries))
+
+	// visit chunks in the order of positions to avoid going backward
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return entries[order[i]].position < entries[order[j]].position
+	})
+	for _, i := range order {
+		if delays[i], err = d.decodeImageDelay(entries[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, idx := range indexes {
+		sizes[idx].Delays = append(sizes[idx].Delays, delays[i])
+	}
+	return &Cursor{Sizes: sizes}, nil
+}
+
+func (d *decoder) inspect() (*midec.Info, error) {
+	c, err := d.decodeCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	// each size is the same animation, so the longest one is reported
+	info := &midec.Info{Kind: midec.KindStatic}
+	for _, s := range c.Sizes {
+		if s.Frames > info.Frames {
+			info.Frames = s.Frames
+		}
+		if s.Frames < 2 {
+			continue
+		}
+		info.Kind = midec.KindAnimated
+		if duration := s.Duration(); duration > info.Duration {
+			info.Duration = duration
+		}
+	}
+	return info, nil
+}
+
+// DecodeCursor reads the table of contents and the header of each image chunk.
+func DecodeCursor(r io.Reader) (*Cursor, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.decodeCursor()
+}
+
+func isAnimated(r io.Reader) (bool, error) {
+	info, err := inspect(r)
+	if err != nil {
+		return false, err
+	}
+	return info.Kind.IsMultiImage(), nil
+}
+
+func inspect(r io.Reader) (*midec.Info, error) {
+	d := decoder{*midec.NewReadAdvancer(r)}
+	return d.inspect()
+}
+
+func init() {
+	midec.RegisterInspectableFormat("xcursor", xcursorHeader, isAnimated, inspect)
+}
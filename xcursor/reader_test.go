@@ -0,0 +1,99 @@
+package xcursor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testdataFolder = "../testdata/xcursor/"
+
+func Test_isAnimated(t *testing.T) {
+	t.Parallel()
+
+	runIsAnimated := func(filename string) (bool, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return isAnimated(fp)
+	}
+
+	testcases := []struct {
+		filename           string
+		expectedIsAnimated bool
+		expectedHasError   bool
+	}{
+		{"animated", true, false},
+		{"static", false, false},
+		{"invalid-header", false, true},
+		{"invalid-noimage", false, true},
+		{"invalid-chunk", false, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actualIsAnimated, actualErr := runIsAnimated(tc.filename)
+			if tc.expectedIsAnimated != actualIsAnimated {
+				t.Errorf("IsAnimated = %t; want %t", actualIsAnimated, tc.expectedIsAnimated)
+			}
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+		})
+	}
+}
+
+func Test_DecodeCursor(t *testing.T) {
+	t.Parallel()
+
+	runDecodeCursor := func(filename string) (*Cursor, error) {
+		fp, err := os.Open(testdataFolder + filename)
+		if err != nil {
+			panic(err)
+		}
+		return DecodeCursor(fp)
+	}
+
+	ms := time.Millisecond
+	testcases := []struct {
+		filename         string
+		expected         *Cursor
+		expectedHasError bool
+	}{
+		{"animated", &Cursor{Sizes: []Size{
+			{NominalSize: 24, Frames: 3, Delays: []time.Duration{50 * ms, 60 * ms, 70 * ms}},
+			{NominalSize: 32, Frames: 3, Delays: []time.Duration{50 * ms, 60 * ms, 80 * ms}},
+		}}, false},
+		{"static", &Cursor{Sizes: []Size{
+			{NominalSize: 24, Frames: 1, Delays: []time.Duration{0}},
+			{NominalSize: 32, Frames: 1, Delays: []time.Duration{0}},
+			{NominalSize: 48, Frames: 1, Delays: []time.Duration{0}},
+		}}, false},
+		{"invalid-chunk", nil, true},
+		{"invalid-noimage", nil, true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			actual, actualErr := runDecodeCursor(tc.filename)
+			if tc.expectedHasError != (actualErr != nil) {
+				t.Errorf("Error = %v; want HasError = %t", actualErr, tc.expectedHasError)
+			}
+			if actualErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Cursor = %+v; want %+v", actual, tc.expected)
+			}
+		})
+	}
+}